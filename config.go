@@ -0,0 +1,166 @@
+package gorion
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ProductMQ is the name of the IronMQ product. Pass it to LoadConfig to load IronMQ configuration
+	ProductMQ = "iron_mq"
+	// ConfigFileName is the name of the Iron.io configuration file that LoadConfig reads
+	// from the working directory. LoadConfig also reads "."+ConfigFileName from the home directory
+	ConfigFileName = "iron.json"
+)
+
+var (
+	// ErrNoToken is returned from LoadConfig when no source has an OAuth token
+	ErrNoToken = errors.New("no Iron.io token configured")
+	// ErrNoProjectID is returned from LoadConfig when no source has a project ID
+	ErrNoProjectID = errors.New("no Iron.io project ID configured")
+
+	// DefaultConfigs holds the default endpoint for each product
+	DefaultConfigs = map[string]Config{
+		ProductMQ: {Scheme: "https", Host: "mq-aws-us-east-1-1.iron.io", Port: 443, APIVersion: "3"},
+	}
+)
+
+// Config holds the credentials and endpoint for an Iron.io service. Its JSON form is the
+// same as the top level (or a product section) of an iron.json file
+type Config struct {
+	Token      string `json:"token"`
+	ProjectID  string `json:"project_id"`
+	Scheme     string `json:"protocol"`
+	Host       string `json:"host"`
+	Port       uint16 `json:"port"`
+	APIVersion string `json:"api_version"`
+}
+
+// merge sets each field in c to the corresponding field in o if that field in o is non-zero
+func (c *Config) merge(o Config) {
+	if o.Token != "" {
+		c.Token = o.Token
+	}
+	if o.ProjectID != "" {
+		c.ProjectID = o.ProjectID
+	}
+	if o.Scheme != "" {
+		c.Scheme = o.Scheme
+	}
+	if o.Host != "" {
+		c.Host = o.Host
+	}
+	if o.Port != 0 {
+		c.Port = o.Port
+	}
+	if o.APIVersion != "" {
+		c.APIVersion = o.APIVersion
+	}
+}
+
+// LoadConfig returns the configuration for product (for example, ProductMQ), following the
+// standard Iron.io resolution order. Each non-empty value in a source overrides the values
+// from the sources after it:
+//
+//  1. explicit
+//  2. environment variables. Product specific variables (for example, IRON_MQ_TOKEN)
+//     override general ones (for example, IRON_TOKEN). The suffixes are TOKEN, PROJECT_ID,
+//     PROTOCOL, HOST, PORT and API_VERSION
+//  3. ./iron.json
+//  4. ~/.iron.json
+//  5. DefaultConfigs[product]
+//
+// In each iron.json file, values in the product section (for example, "iron_mq") override
+// values at the top level. Returns ErrNoToken or ErrNoProjectID if no source has a token
+// or project ID, and a non-nil error if a file exists but can't be parsed
+func LoadConfig(product string, explicit Config) (Config, error) {
+	var paths []string
+	if home := os.Getenv("HOME"); home != "" {
+		paths = append(paths, filepath.Join(home, "."+ConfigFileName))
+	}
+	paths = append(paths, ConfigFileName)
+	return loadConfig(product, explicit, os.Getenv, paths)
+}
+
+// loadConfig is LoadConfig with the environment and the config file paths (in increasing
+// order of precedence) passed in
+func loadConfig(product string, explicit Config, getenv func(string) string, paths []string) (Config, error) {
+	ret := DefaultConfigs[product]
+	for _, path := range paths {
+		conf, err := configFromFile(product, path)
+		if err != nil {
+			return Config{}, err
+		}
+		ret.merge(conf)
+	}
+	envConf, err := configFromEnv("IRON", getenv)
+	if err != nil {
+		return Config{}, err
+	}
+	ret.merge(envConf)
+	envConf, err = configFromEnv(strings.ToUpper(product), getenv)
+	if err != nil {
+		return Config{}, err
+	}
+	ret.merge(envConf)
+	ret.merge(explicit)
+	if ret.Token == "" {
+		return Config{}, ErrNoToken
+	}
+	if ret.ProjectID == "" {
+		return Config{}, ErrNoProjectID
+	}
+	return ret, nil
+}
+
+// configFromFile reads the config for product from the iron.json file at path. Returns
+// an empty config if the file doesn't exist
+func configFromFile(product, path string) (Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Config{}, nil
+	} else if err != nil {
+		return Config{}, err
+	}
+	var ret Config
+	if err := json.Unmarshal(b, &ret); err != nil {
+		return Config{}, fmt.Errorf("parsing %s (%s)", path, err)
+	}
+	sections := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &sections); err != nil {
+		return Config{}, fmt.Errorf("parsing %s (%s)", path, err)
+	}
+	if section, ok := sections[product]; ok {
+		var productConf Config
+		if err := json.Unmarshal(section, &productConf); err != nil {
+			return Config{}, fmt.Errorf("parsing %s section of %s (%s)", product, path, err)
+		}
+		ret.merge(productConf)
+	}
+	return ret, nil
+}
+
+// configFromEnv reads the config from the environment variables that start with prefix + "_"
+func configFromEnv(prefix string, getenv func(string) string) (Config, error) {
+	ret := Config{
+		Token:      getenv(prefix + "_TOKEN"),
+		ProjectID:  getenv(prefix + "_PROJECT_ID"),
+		Scheme:     getenv(prefix + "_PROTOCOL"),
+		Host:       getenv(prefix + "_HOST"),
+		APIVersion: getenv(prefix + "_API_VERSION"),
+	}
+	if portStr := getenv(prefix + "_PORT"); portStr != "" {
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid %s_PORT [%s]", prefix, portStr)
+		}
+		ret.Port = uint16(port)
+	}
+	return ret, nil
+}
@@ -0,0 +1,67 @@
+package gorion
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arschles/assert"
+)
+
+func writeConfigFile(t *testing.T, dir, name, contents string) string {
+	path := filepath.Join(dir, name)
+	assert.NoErr(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	return path
+}
+
+func envFunc(env map[string]string) func(string) string {
+	return func(key string) string {
+		return env[key]
+	}
+}
+
+func TestLoadConfigOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gorion-config")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+	home := writeConfigFile(t, dir, "home.json", `{"token":"home-token","project_id":"home-proj","host":"home-host","iron_mq":{"port":8080}}`)
+	local := writeConfigFile(t, dir, "local.json", `{"project_id":"local-proj","iron_mq":{"host":"local-mq-host"}}`)
+	env := map[string]string{"IRON_TOKEN": "env-token", "IRON_MQ_PROTOCOL": "http"}
+	conf, err := loadConfig(ProductMQ, Config{Token: "explicit-token"}, envFunc(env), []string{home, local})
+	assert.NoErr(t, err)
+	assert.Equal(t, "explicit-token", conf.Token, "token")
+	assert.Equal(t, "local-proj", conf.ProjectID, "project ID")
+	assert.Equal(t, "local-mq-host", conf.Host, "host")
+	assert.Equal(t, uint16(8080), conf.Port, "port")
+	assert.Equal(t, "http", conf.Scheme, "scheme")
+	assert.Equal(t, DefaultConfigs[ProductMQ].APIVersion, conf.APIVersion, "API version")
+}
+
+func TestLoadConfigEnvOverridesFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gorion-config")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+	local := writeConfigFile(t, dir, "local.json", `{"token":"local-token","project_id":"local-proj","iron_mq":{"token":"local-mq-token"}}`)
+	env := map[string]string{"IRON_TOKEN": "env-token", "IRON_MQ_TOKEN": "env-mq-token", "IRON_PORT": "1234"}
+	conf, err := loadConfig(ProductMQ, Config{}, envFunc(env), []string{filepath.Join(dir, "missing.json"), local})
+	assert.NoErr(t, err)
+	assert.Equal(t, "env-mq-token", conf.Token, "token")
+	assert.Equal(t, "local-proj", conf.ProjectID, "project ID")
+	assert.Equal(t, uint16(1234), conf.Port, "port")
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gorion-config")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+	_, err = loadConfig(ProductMQ, Config{ProjectID: "proj"}, envFunc(nil), nil)
+	assert.Err(t, ErrNoToken, err)
+	_, err = loadConfig(ProductMQ, Config{Token: "token"}, envFunc(nil), nil)
+	assert.Err(t, ErrNoProjectID, err)
+	bad := writeConfigFile(t, dir, "bad.json", `{"token":`)
+	_, err = loadConfig(ProductMQ, Config{}, envFunc(nil), []string{bad})
+	assert.ExistsErr(t, err, "malformed config file")
+	_, err = loadConfig(ProductMQ, Config{}, envFunc(map[string]string{"IRON_PORT": "abc"}), nil)
+	assert.ExistsErr(t, err, "invalid port")
+}
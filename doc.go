@@ -5,5 +5,8 @@
 // Additionally, all interface funcs take a net.Context (http://godoc.org/golang.org/x/net/context) which your code can use
 // to control timeouts, cancellation and more. See https://blog.golang.org/context for more information on how to use Contexts.
 //
+// Use LoadConfig to read Iron.io credentials and endpoints from explicit values, IRON_* environment variables
+// and iron.json files, in the same order as the official Iron.io client libraries.
+//
 // Gorion currently supports a small subset of the IronMQ API. See the mq package for more usage details.
 package gorion
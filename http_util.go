@@ -13,13 +13,20 @@ var (
 	ErrCancelled = errors.New("cancelled")
 )
 
+// RequestCanceler is implemented by http.RoundTrippers that can cancel in-flight requests.
+// *http.Transport implements it
+type RequestCanceler interface {
+	CancelRequest(*http.Request)
+}
+
 // HTTPDo runs the HTTP request in a goroutine and passes the response to f in
 // that same goroutine. After the goroutine finishes, returns the result of f.
-// If ctx.Done() receives before f returns, calls transport.CancelRequest(req) before
-// before returning. Even though HTTPDo executes f in another goroutine, you
-// can treat it as a synchronous call to f. Also, since HTTPDo uses client to run
-// requests and transport to cancel them, client.Transport should be transport
-// in most cases.
+// HTTPDo sends req with ctx attached, so any http.RoundTripper that honors request
+// contexts aborts it when ctx.Done() receives. If ctx.Done() receives before f returns
+// and canceler is non-nil, HTTPDo also calls canceler.CancelRequest before returning.
+// Even though HTTPDo executes f in another goroutine, you can treat it as a synchronous
+// call to f. Since HTTPDo uses client to run requests and canceler to cancel them,
+// canceler should be client.Transport (or nil) in most cases.
 //
 // Example Usage:
 //  type Resp struct { Num int `json:"num"` }
@@ -37,7 +44,7 @@ var (
 //  // do something with resp...
 //
 // This func was stolen/adapted from https://blog.golang.org/context
-func HTTPDo(ctx context.Context, client *http.Client, transport *http.Transport, req *http.Request, f func(*http.Response, error) error) error {
+func HTTPDo(ctx context.Context, client *http.Client, canceler RequestCanceler, req *http.Request, f func(*http.Response, error) error) error {
 	// Run the HTTP request in a goroutine and pass the response to f.
 	c := make(chan error, 1)
 
@@ -48,13 +55,16 @@ func HTTPDo(ctx context.Context, client *http.Client, transport *http.Transport,
 	default:
 	}
 
+	req = req.WithContext(ctx)
 	go func() {
 		c <- f(client.Do(req))
 	}()
 
 	select {
 	case <-ctx.Done():
-		transport.CancelRequest(req)
+		if canceler != nil {
+			canceler.CancelRequest(req)
+		}
 		<-c // Wait for f to return.
 		return ctx.Err()
 	case err := <-c:
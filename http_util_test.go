@@ -44,3 +44,27 @@ func TestHTTPDoAfterCancel(t *testing.T) {
 	recv := srv.AcceptN(1, 100*time.Millisecond)
 	assert.Equal(t, 0, len(recv), "number of received requests")
 }
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (r roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+func TestHTTPDoCancelWithoutCanceler(t *testing.T) {
+	done := make(chan struct{})
+	hndl := func(http.ResponseWriter, *http.Request) { <-done }
+	srv := testsrv.StartServer(http.HandlerFunc(hndl))
+	defer srv.Close()
+	defer close(done)
+	// wrap the default transport so that HTTPDo can't use CancelRequest
+	client := &http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}
+	req, err := http.NewRequest("GET", srv.URLStr(), strings.NewReader(""))
+	assert.NoErr(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = HTTPDo(ctx, client, nil, req, func(resp *http.Response, err error) error {
+		return err
+	})
+	assert.Err(t, context.DeadlineExceeded, err)
+}
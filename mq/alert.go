@@ -0,0 +1,76 @@
+package mq
+
+const (
+	// AlertTypeFixed is the type of an alert that fires when the queue size crosses its trigger
+	AlertTypeFixed = "fixed"
+	// AlertTypeProgressive is the type of an alert that fires each time the queue size crosses
+	// a multiple of its trigger
+	AlertTypeProgressive = "progressive"
+	// AlertDirectionAsc makes an alert fire when the queue size grows past the trigger
+	AlertDirectionAsc = "asc"
+	// AlertDirectionDesc makes an alert fire when the queue size shrinks past the trigger
+	AlertDirectionDesc = "desc"
+)
+
+// Alert posts a message to another queue when the size of the queue it's on crosses a threshold
+type Alert struct {
+	// ID is the ID of the alert. IronMQ assigns it when the alert is added
+	ID string `json:"id,omitempty"`
+	// Type is the alert type. One of AlertTypeFixed or AlertTypeProgressive
+	Type string `json:"type"`
+	// Trigger is the queue size threshold
+	Trigger int `json:"trigger"`
+	// Direction is the direction that the queue size must cross the trigger in. One of
+	// AlertDirectionAsc or AlertDirectionDesc. IronMQ uses AlertDirectionAsc if it's empty
+	Direction string `json:"direction,omitempty"`
+	// Queue is the name of the queue that alert messages are posted to
+	Queue string `json:"queue"`
+	// Snooze is the minimum number of seconds between two firings of the alert
+	Snooze int `json:"snooze,omitempty"`
+}
+
+// validate returns ErrInvalidAlert if a is missing a queue or has an invalid type, trigger or direction
+func (a Alert) validate() error {
+	if a.Type != AlertTypeFixed && a.Type != AlertTypeProgressive {
+		return ErrInvalidAlert
+	}
+	if a.Direction != "" && a.Direction != AlertDirectionAsc && a.Direction != AlertDirectionDesc {
+		return ErrInvalidAlert
+	}
+	if a.Trigger <= 0 || a.Queue == "" || a.Snooze < 0 {
+		return ErrInvalidAlert
+	}
+	return nil
+}
+
+// fires returns true if a queue size change from prev to cur should fire a
+func (a Alert) fires(prev, cur int) bool {
+	if a.Direction == AlertDirectionDesc {
+		// the largest multiple of the trigger below prev
+		k := (prev - 1) / a.Trigger
+		if a.Type == AlertTypeFixed && k > 1 {
+			k = 1
+		}
+		return k >= 1 && cur <= k*a.Trigger
+	}
+	if a.Type == AlertTypeFixed {
+		return prev < a.Trigger && cur >= a.Trigger
+	}
+	return cur >= a.Trigger && cur/a.Trigger > prev/a.Trigger
+}
+
+// AlertMessage is the body of the message that an alert posts to its queue
+type AlertMessage struct {
+	// AlertID is the ID of the alert that fired
+	AlertID string `json:"alert_id"`
+	// AlertType is the type of the alert that fired
+	AlertType string `json:"alert_type"`
+	// AlertDirection is the direction of the alert that fired
+	AlertDirection string `json:"alert_direction"`
+	// AlertTrigger is the trigger of the alert that fired
+	AlertTrigger int `json:"alert_trigger"`
+	// SourceQueue is the name of the queue that the alert is on
+	SourceQueue string `json:"source_queue"`
+	// QueueSize is the size of the source queue when the alert fired
+	QueueSize int `json:"queue_size"`
+}
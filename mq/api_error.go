@@ -0,0 +1,131 @@
+package mq
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is returned from HTTPClient funcs when IronMQ responds with a non-2xx status code.
+// Funcs that document a sentinel error (for example, ErrNoSuchQueue) return that error
+// instead of an APIError when the response means it. A response means a sentinel error if
+// it has the status code that IronMQ uses for the error and its message is exactly the
+// error's text, which is what servers like mqtest respond with, or IronMQ's own message for
+// it ("Queue not found" for ErrNoSuchQueue and "Message not found" for ErrNoSuchMessage).
+// Funcs also return ErrNoSuchQueue, ErrNoSuchMessage or ErrQueueExists for other 404 and 409
+// responses, as they document
+type APIError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Msg is the error message that IronMQ returned, or the raw response body if it had no message
+	Msg string
+	// Method is the HTTP method of the request
+	Method string
+	// Path is the URL path of the request
+	Path string
+	// the delay that IronMQ asked for in the Retry-After header, if any
+	retryAfter time.Duration
+}
+
+// Error is the error interface implementation
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s returned %d [%s]", e.Method, e.Path, e.StatusCode, e.Msg)
+}
+
+type apiErrorResp struct {
+	Msg string `json:"msg"`
+}
+
+// newAPIError creates an APIError from req and its non-2xx response resp. Reads but doesn't close resp.Body
+func newAPIError(req *http.Request, resp *http.Response) *APIError {
+	ret := &APIError{StatusCode: resp.StatusCode, Method: req.Method, Path: req.URL.Path}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+		ret.retryAfter = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(resp.Header.Get("Retry-After")); err == nil {
+		ret.retryAfter = t.Sub(time.Now())
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		ret.Msg = http.StatusText(resp.StatusCode)
+		return ret
+	}
+	errResp := new(apiErrorResp)
+	if err := json.Unmarshal(body, errResp); err == nil && errResp.Msg != "" {
+		ret.Msg = errResp.Msg
+	} else {
+		ret.Msg = strings.TrimSpace(string(body))
+	}
+	return ret
+}
+
+// sentinelErrs are the sentinel errors that an IronMQ error response can mean, keyed by the
+// status code that IronMQ responds with for each one
+var sentinelErrs = map[int][]error{
+	http.StatusNotFound: {ErrNoSuchQueue, ErrNoSuchMessage, ErrNoSuchReservation, ErrNoSuchAlert},
+	http.StatusConflict: {ErrQueueExists},
+	http.StatusBadRequest: {ErrTimeoutOutOfRange, ErrWaitOutOfRange, ErrDelayOutOfRange, ErrExpirationOutOfRange,
+		ErrPerPageOutOfRange, ErrInvalidQueueType, ErrNoSubscribers, ErrInvalidSubscriber, ErrNotPushQueue, ErrInvalidAlert},
+}
+
+// ironMQMsgs maps the messages that IronMQ itself responds with to the sentinel errors that
+// they mean. IronMQ responds with them with a 404 status code
+var ironMQMsgs = map[string]error{
+	"Queue not found":   ErrNoSuchQueue,
+	"Message not found": ErrNoSuchMessage,
+}
+
+// sentinelErr returns the sentinel error that an IronMQ response with the given status code
+// and message means, or nil if it doesn't mean one. See APIError for the responses that mean
+// sentinel errors
+func sentinelErr(code int, msg string) error {
+	for _, sentinel := range sentinelErrs[code] {
+		if msg == sentinel.Error() {
+			return sentinel
+		}
+	}
+	if sentinel, ok := ironMQMsgs[msg]; ok && code == http.StatusNotFound {
+		return sentinel
+	}
+	return nil
+}
+
+// queueErr converts err to the sentinel error it means if it's an *APIError, or to
+// ErrNoSuchQueue or ErrQueueExists if it's an *APIError with a 404 or 409 status code,
+// respectively. Otherwise returns err
+func queueErr(err error) error {
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		return err
+	}
+	if sentinel := sentinelErr(apiErr.StatusCode, apiErr.Msg); sentinel != nil {
+		return sentinel
+	}
+	switch apiErr.StatusCode {
+	case http.StatusNotFound:
+		return ErrNoSuchQueue
+	case http.StatusConflict:
+		return ErrQueueExists
+	default:
+		return err
+	}
+}
+
+// messageErr converts err to the sentinel error it means if it's an *APIError, or to
+// ErrNoSuchMessage if it's an *APIError with a 404 status code. Otherwise returns err
+func messageErr(err error) error {
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		return err
+	}
+	if sentinel := sentinelErr(apiErr.StatusCode, apiErr.Msg); sentinel != nil {
+		return sentinel
+	}
+	if apiErr.StatusCode == http.StatusNotFound {
+		return ErrNoSuchMessage
+	}
+	return err
+}
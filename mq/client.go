@@ -3,6 +3,7 @@ package mq
 import (
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
 
 	"golang.org/x/net/context"
@@ -59,6 +60,12 @@ const (
 	MinWait = 0
 	// MaxWait is the maximum value for a wait
 	MaxWait = 30
+	// MaxDelay is the maximum number of seconds that a message can be delayed before it goes onto a queue
+	MaxDelay = 604800
+	// DefaultPerPage is the number of queues that ListQueues returns when it's passed a perPage of 0
+	DefaultPerPage = 30
+	// MaxPerPage is the maximum number of queues that ListQueues can return
+	MaxPerPage = 100
 )
 
 var (
@@ -66,12 +73,35 @@ var (
 	ErrTimeoutOutOfRange = fmt.Errorf("timeout out of range [%d, %d]", MinTimeout, MaxTimeout)
 	// ErrWaitOutOfRange is returned when a Wait is given that's out of the [MinWait, MaxTimeout] range
 	ErrWaitOutOfRange = fmt.Errorf("wait out of range [%d, %d]", MinWait, MaxWait)
+	// ErrDelayOutOfRange is returned when a delay is given that's greater than MaxDelay
+	ErrDelayOutOfRange = fmt.Errorf("delay out of range [0, %d]", MaxDelay)
 	// ErrNoSuchReservation is returned from funcs that accept a reservation ID
 	// when the ID doesn't exist
 	ErrNoSuchReservation = errors.New("no such reservation")
 	// ErrNoSuchMessage is returned from funcs that accept a message ID when the
 	// ID doesn't exist
 	ErrNoSuchMessage = errors.New("no such message")
+	// ErrNoSuchQueue is returned from funcs that accept a queue name when the
+	// queue doesn't exist
+	ErrNoSuchQueue = errors.New("no such queue")
+	// ErrQueueExists is returned from CreateQueue when the queue already exists
+	ErrQueueExists = errors.New("queue already exists")
+	// ErrExpirationOutOfRange is returned when a message expiration is given that's greater than MaxMessageExpiration
+	ErrExpirationOutOfRange = fmt.Errorf("message expiration out of range [0, %d]", MaxMessageExpiration)
+	// ErrInvalidQueueType is returned when a queue configuration has an unsupported queue type
+	ErrInvalidQueueType = errors.New("invalid queue type")
+	// ErrNoSubscribers is returned when a push queue configuration has no subscribers
+	ErrNoSubscribers = errors.New("push queues must have at least one subscriber")
+	// ErrInvalidSubscriber is returned when a subscriber is missing a name or URL
+	ErrInvalidSubscriber = errors.New("subscribers must have a name and a URL")
+	// ErrNotPushQueue is returned from subscriber funcs when the queue isn't a push queue
+	ErrNotPushQueue = errors.New("not a push queue")
+	// ErrInvalidAlert is returned when an alert is missing a queue or has an invalid type, trigger or direction
+	ErrInvalidAlert = errors.New("invalid alert")
+	// ErrNoSuchAlert is returned from DeleteAlert when the queue has no alert with the given ID
+	ErrNoSuchAlert = errors.New("no such alert")
+	// ErrPerPageOutOfRange is returned from ListQueues when perPage is out of the [0, MaxPerPage] range
+	ErrPerPageOutOfRange = fmt.Errorf("per page out of range [0, %d]", MaxPerPage)
 )
 
 // Enqueued is the result of the Enqueue func
@@ -82,11 +112,71 @@ type Enqueued struct {
 	Msg string `json:"msg"`
 }
 
-// Deleted is the result of the DeleteReserved func
+// Deleted is the result of the DeleteReserved and DeleteQueue funcs
 type Deleted struct {
 	Msg string `json:"msg"`
 }
 
+// ReservedMessage identifies a single reserved message. It's passed to DeleteReservedBatch
+type ReservedMessage struct {
+	ID            string `json:"id"`
+	ReservationID string `json:"reservation_id"`
+}
+
+// DeleteResult is the result of deleting a single message in a DeleteReservedBatch call
+type DeleteResult struct {
+	// ID is the ID of the message
+	ID string `json:"id"`
+	// Msg is the status of the delete operation for the message
+	Msg string `json:"msg"`
+	// Err is nil if the message was deleted. Otherwise it's ErrNoSuchReservation or
+	// ErrNoSuchMessage if the reservation or message didn't exist, or another non-nil
+	// error describing why the message wasn't deleted
+	Err error `json:"-"`
+}
+
+// DeletedBatch is the result of the DeleteReservedBatch func
+type DeletedBatch struct {
+	// Results holds the result of each delete, in the same order as the messages that were passed to DeleteReservedBatch
+	Results []DeleteResult `json:"ids"`
+	Msg     string         `json:"msg"`
+}
+
+// Cleared is the result of the ClearQueue func
+type Cleared struct {
+	Msg string `json:"msg"`
+}
+
+// Updated is the result of funcs that update part of a queue's configuration, like AddSubscribers
+type Updated struct {
+	Msg string `json:"msg"`
+}
+
+// Touched is the result of the Touch func
+type Touched struct {
+	// ReservationID is the ID of the new reservation. Use it in place of the old reservation ID
+	ReservationID string `json:"reservation_id"`
+	Msg           string `json:"msg"`
+}
+
+// Released is the result of the Release func
+type Released struct {
+	Msg string `json:"msg"`
+}
+
+// WebhookPoster is implemented by clients that can enqueue raw webhook bodies. HTTPClient
+// and MemClient both implement it. It's kept out of Client so that other Client
+// implementations don't need to support webhooks.
+type WebhookPoster interface {
+	// PostWebhook enqueues a single message onto qName whose body is everything read from
+	// body. It's intended for bodies that come from third-party webhooks, which aren't in
+	// the NewMessage format.
+	//
+	// Returns nil and a non-nil error if ctx.Done() receives before the operation succeeds,
+	// reading body fails or any other error occurs.
+	PostWebhook(ctx context.Context, token, projID, qName string, body io.Reader) (*Enqueued, error)
+}
+
 // Client is an interface for communicating with the IronMQ service.
 type Client interface {
 	// Enqueue enqueues msgs onto qName. if ctx.Done() receives before the enqueue
@@ -121,5 +211,153 @@ type Client interface {
 	//
 	// Note that clients need not roll back a partially applied delete operation
 	// if ctx.Done() received before it finished
-	DeleteReserved(ctx context.Context, token, projID, qName string, messageID int, reservationID string) (*Deleted, error)
+	DeleteReserved(ctx context.Context, token, projID, qName string, messageID string, reservationID string) (*Deleted, error)
+
+	// DeleteReservedBatch deletes all of the given reserved messages from qName in a
+	// single operation. The returned DeletedBatch has a DeleteResult for each message in
+	// msgs, so a failure to delete some messages doesn't fail the whole operation. Check
+	// the Err field of each result to find the messages that weren't deleted. If msgs is
+	// empty, nothing is deleted and the DeletedBatch has no results.
+	//
+	// Returns nil and an error if ctx.Done() receives before the delete operation
+	// succeeds or any other error prevents the whole batch from being processed.
+	//
+	// Note that clients need not roll back a partially applied delete operation
+	// if ctx.Done() received before it finished
+	DeleteReservedBatch(ctx context.Context, token, projID, qName string, msgs []ReservedMessage) (*DeletedBatch, error)
+
+	// CreateQueue creates a new queue called qName with the given configuration and
+	// returns information about the new queue.
+	//
+	// Returns nil and ErrQueueExists if the queue already exists, and nil and a non-nil
+	// error if ctx.Done() receives before the create operation succeeds or any other
+	// error occurs.
+	CreateQueue(ctx context.Context, token, projID, qName string, conf QueueConfig) (*QueueInfo, error)
+
+	// GetQueue returns information about the queue called qName.
+	//
+	// Returns nil and ErrNoSuchQueue if the queue doesn't exist, and nil and a non-nil
+	// error if ctx.Done() receives before the operation succeeds or any other error occurs.
+	GetQueue(ctx context.Context, token, projID, qName string) (*QueueInfo, error)
+
+	// UpdateQueue updates the queue called qName with the non-zero values in conf and
+	// returns information about the updated queue. Changing a push queue to a pull queue
+	// removes its push configuration, including its subscribers.
+	//
+	// Returns nil and ErrNoSuchQueue if the queue doesn't exist, and nil and a non-nil
+	// error if ctx.Done() receives before the update operation succeeds or any other
+	// error occurs.
+	UpdateQueue(ctx context.Context, token, projID, qName string, conf QueueConfig) (*QueueInfo, error)
+
+	// DeleteQueue deletes the queue called qName and all of the messages on it.
+	//
+	// Returns nil and ErrNoSuchQueue if the queue doesn't exist, and nil and a non-nil
+	// error if ctx.Done() receives before the delete operation succeeds or any other
+	// error occurs.
+	DeleteQueue(ctx context.Context, token, projID, qName string) (*Deleted, error)
+
+	// ClearQueue deletes all of the messages on the queue called qName, including reserved
+	// messages, but leaves the queue itself and its configuration in place.
+	//
+	// Returns nil and ErrNoSuchQueue if the queue doesn't exist, and nil and a non-nil
+	// error if ctx.Done() receives before the clear operation succeeds or any other
+	// error occurs.
+	ClearQueue(ctx context.Context, token, projID, qName string) (*Cleared, error)
+
+	// AddSubscribers adds subs to the push queue called qName. Existing subscribers with
+	// the same names as any of subs are replaced.
+	//
+	// Returns nil and ErrNoSuchQueue if the queue doesn't exist, nil and ErrNotPushQueue if
+	// it's not a push queue, nil and ErrInvalidSubscriber if any of subs is invalid, and nil
+	// and a non-nil error if ctx.Done() receives before the operation succeeds or any other
+	// error occurs.
+	AddSubscribers(ctx context.Context, token, projID, qName string, subs []Subscriber) (*Updated, error)
+
+	// ReplaceSubscribers replaces all of the subscribers on the push queue called qName with
+	// subs. Returns the same errors as AddSubscribers, and ErrNoSubscribers if subs is empty.
+	ReplaceSubscribers(ctx context.Context, token, projID, qName string, subs []Subscriber) (*Updated, error)
+
+	// RemoveSubscribers removes the subscribers with the given names from the push queue
+	// called qName. Names that don't match a subscriber are ignored. Returns the same
+	// errors as AddSubscribers, and nil and ErrNoSubscribers if it would remove every
+	// subscriber.
+	RemoveSubscribers(ctx context.Context, token, projID, qName string, names []string) (*Updated, error)
+
+	// AddAlerts adds alerts to the queue called qName. An alert whose queue is qName is
+	// invalid.
+	//
+	// Returns nil and ErrNoSuchQueue if the queue doesn't exist, nil and ErrInvalidAlert if
+	// any of alerts is invalid, and nil and a non-nil error if ctx.Done() receives before
+	// the operation succeeds or any other error occurs.
+	AddAlerts(ctx context.Context, token, projID, qName string, alerts []Alert) (*Updated, error)
+
+	// ReplaceAlerts replaces all of the alerts on the queue called qName with alerts. Pass
+	// an empty slice to remove all alerts. Returns the same errors as AddAlerts.
+	ReplaceAlerts(ctx context.Context, token, projID, qName string, alerts []Alert) (*Updated, error)
+
+	// DeleteAlert removes the alert with the given alert ID from the queue called qName.
+	//
+	// Returns nil and ErrNoSuchQueue if the queue doesn't exist, nil and ErrNoSuchAlert if
+	// the queue has no alert with that ID, and nil and a non-nil error if ctx.Done()
+	// receives before the operation succeeds or any other error occurs.
+	DeleteAlert(ctx context.Context, token, projID, qName, alertID string) (*Deleted, error)
+
+	// ListQueues returns at most perPage queues in projID, sorted by name. Only queues
+	// whose names start with prefix and come after previous are returned, so pass the
+	// name of the last queue in one page as previous to get the next page. Pass an empty
+	// prefix to list all queues, an empty previous to start at the first page and 0 as
+	// perPage to get DefaultPerPage queues. Only the Name and ProjectID fields of the
+	// returned QueueInfos are guaranteed to be set. See QueueIterator for a simple way to
+	// walk all pages.
+	//
+	// Returns nil and ErrPerPageOutOfRange if perPage is out of range, and nil and a
+	// non-nil error if ctx.Done() receives before the list operation succeeds or any
+	// other error occurs.
+	ListQueues(ctx context.Context, token, projID, prefix, previous string, perPage int) ([]QueueInfo, error)
+
+	// Peek returns at most num messages from the front of qName without reserving them,
+	// so peeking never changes a message's ReservedCount or makes it unavailable to
+	// consumers. Pass 0 as num to peek at a single message.
+	//
+	// Returns nil and a non-nil error if ctx.Done() receives before the peek operation
+	// succeeds or any other error occurs.
+	Peek(ctx context.Context, token, projID, qName string, num int) ([]Message, error)
+
+	// GetMessage returns the message with the given message ID from qName, whether or
+	// not it's reserved. Getting a message never reserves it.
+	//
+	// Returns nil and ErrNoSuchMessage if messageID refers to a message that isn't in the
+	// queue, and nil and a non-nil error if ctx.Done() receives before the get operation
+	// succeeds or any other error occurs.
+	GetMessage(ctx context.Context, token, projID, qName string, messageID string) (*Message, error)
+
+	// GetPushStatuses returns the delivery status of the message with the given message ID
+	// for each subscriber on the push queue called qName.
+	//
+	// Returns nil and ErrNoSuchMessage if messageID refers to a message that was never
+	// pushed from the queue, and nil and a non-nil error if ctx.Done() receives before the
+	// operation succeeds or any other error occurs.
+	GetPushStatuses(ctx context.Context, token, projID, qName string, messageID string) ([]PushStatus, error)
+
+	// Touch extends the reservation with the given reservation ID on the message with
+	// the given message ID, so the message doesn't go back onto the queue until timeout
+	// from now. Pass 0 as timeout to use the queue's message timeout. The old reservation
+	// ID is invalid after a successful touch, so callers must use the ReservationID in
+	// the result for all subsequent operations on the message.
+	//
+	// Returns nil and ErrNoSuchReservation if reservationID refers to a reservation that
+	// doesn't exist in the queue, nil and ErrTimeoutOutOfRange if timeout is non-zero and
+	// out of range, and nil and a non-nil error if ctx.Done() receives before the touch
+	// operation succeeds or any other error occurs.
+	Touch(ctx context.Context, token, projID, qName string, messageID string, reservationID string, timeout Timeout) (*Touched, error)
+
+	// Release releases the reservation with the given reservation ID on the message with
+	// the given message ID, so the message goes back onto the queue after delay seconds
+	// instead of when the reservation expires.
+	//
+	// Returns nil and ErrNoSuchReservation if reservationID refers to a reservation that
+	// doesn't exist in the queue, nil and ErrDelayOutOfRange if delay is greater than
+	// MaxDelay, and nil and a non-nil error if ctx.Done() receives before the release
+	// operation succeeds or any other error occurs.
+	Release(ctx context.Context, token, projID, qName string, messageID string, reservationID string, delay uint32) (*Released, error)
 }
@@ -2,6 +2,7 @@ package mq
 
 import (
 	"fmt"
+	"strings"
 
 	"golang.org/x/net/context"
 )
@@ -48,3 +49,431 @@ func qOperations(cl Client) error {
 	}
 	return nil
 }
+
+func qLifecycle(cl Client) error {
+	ctx := context.Background()
+	created, err := cl.CreateQueue(ctx, token, projID, qName, QueueConfig{MessageTimeout: 120})
+	if err != nil {
+		return fmt.Errorf("got error on create [%s]", err)
+	}
+	if created.Name != qName || created.ProjectID != projID {
+		return fmt.Errorf("created queue [%s/%s], expected [%s/%s]", created.ProjectID, created.Name, projID, qName)
+	}
+	if created.MessageTimeout != 120 {
+		return fmt.Errorf("created queue message timeout was [%d], expected 120", created.MessageTimeout)
+	}
+	if created.MessageExpiration != DefaultMessageExpiration {
+		return fmt.Errorf("created queue message expiration was [%d], expected the default [%d]", created.MessageExpiration, DefaultMessageExpiration)
+	}
+	newMsgs := []NewMessage{{Body: "123", Delay: 0, PushHeaders: make(map[string]string)}}
+	if _, err := cl.Enqueue(ctx, token, projID, qName, newMsgs); err != nil {
+		return fmt.Errorf("got error on enqueue [%s]", err)
+	}
+	info, err := cl.GetQueue(ctx, token, projID, qName)
+	if err != nil {
+		return fmt.Errorf("got error on get [%s]", err)
+	}
+	if info.Size != 1 || info.TotalMessages != 1 {
+		return fmt.Errorf("queue size was [%d] and total messages was [%d], expected 1 and 1", info.Size, info.TotalMessages)
+	}
+	updated, err := cl.UpdateQueue(ctx, token, projID, qName, QueueConfig{MessageExpiration: 3600})
+	if err != nil {
+		return fmt.Errorf("got error on update [%s]", err)
+	}
+	if updated.MessageTimeout != 120 || updated.MessageExpiration != 3600 {
+		return fmt.Errorf("updated queue message timeout was [%d] and expiration was [%d], expected 120 and 3600", updated.MessageTimeout, updated.MessageExpiration)
+	}
+	deleted, err := cl.DeleteQueue(ctx, token, projID, qName)
+	if err != nil {
+		return fmt.Errorf("got error on delete [%s]", err)
+	}
+	if len(deleted.Msg) <= 0 {
+		return fmt.Errorf("DeleteQueue returned an empty message")
+	}
+	return nil
+}
+
+func qListQueues(cl Client) error {
+	ctx := context.Background()
+	names := []string{"a3", "b1", "a1", "a2"}
+	for _, name := range names {
+		if _, err := cl.CreateQueue(ctx, token, projID, name, QueueConfig{}); err != nil {
+			return fmt.Errorf("got error creating queue [%s] (%s)", name, err)
+		}
+	}
+	page, err := cl.ListQueues(ctx, token, projID, "a", "", 2)
+	if err != nil {
+		return fmt.Errorf("got error on list [%s]", err)
+	}
+	if len(page) != 2 || page[0].Name != "a1" || page[1].Name != "a2" {
+		return fmt.Errorf("first page was %+v, expected queues a1 and a2", page)
+	}
+	var listed []string
+	iter := NewQueueIterator(cl, token, projID, "a", 2)
+	for iter.Next(ctx) {
+		listed = append(listed, iter.Queue().Name)
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("got error iterating [%s]", err)
+	}
+	if fmt.Sprint(listed) != "[a1 a2 a3]" {
+		return fmt.Errorf("iterated over queues %v, expected [a1 a2 a3]", listed)
+	}
+	return nil
+}
+
+func qPeek(cl Client) error {
+	ctx := context.Background()
+	newMsgs := []NewMessage{
+		{Body: "1", Delay: 0, PushHeaders: make(map[string]string)},
+		{Body: "2", Delay: 0, PushHeaders: make(map[string]string)},
+		{Body: "3", Delay: 0, PushHeaders: make(map[string]string)},
+	}
+	if _, err := cl.Enqueue(ctx, token, projID, qName, newMsgs); err != nil {
+		return fmt.Errorf("got error on enqueue [%s]", err)
+	}
+	// peek twice to make sure that peeking doesn't change the queue
+	for i := 0; i < 2; i++ {
+		peeked, err := cl.Peek(ctx, token, projID, qName, 2)
+		if err != nil {
+			return fmt.Errorf("got error on peek [%s]", err)
+		}
+		if len(peeked) != 2 {
+			return fmt.Errorf("peek returned [%d] messages, expected 2", len(peeked))
+		}
+		for j, msg := range peeked {
+			if msg.Body != newMsgs[j].Body {
+				return fmt.Errorf("peeked message # [%d] body [%s] isn't enqueued message body [%s]", j, msg.Body, newMsgs[j].Body)
+			}
+			if msg.ReservedCount != 0 {
+				return fmt.Errorf("peeked message # [%d] was reserved [%d] times", j, msg.ReservedCount)
+			}
+		}
+	}
+	return nil
+}
+
+func qTouchRelease(cl Client) error {
+	ctx := context.Background()
+	newMsgs := []NewMessage{{Body: "123", Delay: 0, PushHeaders: make(map[string]string)}}
+	if _, err := cl.Enqueue(ctx, token, projID, qName, newMsgs); err != nil {
+		return fmt.Errorf("got error on enqueue [%s]", err)
+	}
+	dqMsgs, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(1), false)
+	if err != nil {
+		return fmt.Errorf("got error on dequeue [%s]", err)
+	}
+	if len(dqMsgs) != 1 {
+		return fmt.Errorf("dequeued [%d] messages, expected 1", len(dqMsgs))
+	}
+	dqMsg := dqMsgs[0]
+	touched, err := cl.Touch(ctx, token, projID, qName, dqMsg.ID, dqMsg.ReservationID, Timeout(60))
+	if err != nil {
+		return fmt.Errorf("got error on touch [%s]", err)
+	}
+	if touched.ReservationID == "" || touched.ReservationID == dqMsg.ReservationID {
+		return fmt.Errorf("touch returned reservation ID [%s], expected a new one", touched.ReservationID)
+	}
+	if _, err := cl.Release(ctx, token, projID, qName, dqMsg.ID, dqMsg.ReservationID, 0); err == nil {
+		return fmt.Errorf("release with the old reservation ID succeeded")
+	}
+	if _, err := cl.Release(ctx, token, projID, qName, dqMsg.ID, touched.ReservationID, 0); err != nil {
+		return fmt.Errorf("got error on release [%s]", err)
+	}
+	peeked, err := cl.Peek(ctx, token, projID, qName, 1)
+	if err != nil {
+		return fmt.Errorf("got error on peek [%s]", err)
+	}
+	if len(peeked) != 1 || peeked[0].ID != dqMsg.ID {
+		return fmt.Errorf("peeked %+v after release, expected message [%s]", peeked, dqMsg.ID)
+	}
+	return nil
+}
+
+func qDeleteReservedBatch(cl Client) error {
+	ctx := context.Background()
+	newMsgs := []NewMessage{
+		{Body: "1", Delay: 0, PushHeaders: make(map[string]string)},
+		{Body: "2", Delay: 0, PushHeaders: make(map[string]string)},
+	}
+	if _, err := cl.Enqueue(ctx, token, projID, qName, newMsgs); err != nil {
+		return fmt.Errorf("got error on enqueue [%s]", err)
+	}
+	dqMsgs, err := cl.Dequeue(ctx, token, projID, qName, 2, Timeout(30), Wait(1), false)
+	if err != nil {
+		return fmt.Errorf("got error on dequeue [%s]", err)
+	}
+	if len(dqMsgs) != 2 {
+		return fmt.Errorf("dequeued [%d] messages, expected 2", len(dqMsgs))
+	}
+	toDelete := []ReservedMessage{
+		{ID: dqMsgs[0].ID, ReservationID: dqMsgs[0].ReservationID},
+		{ID: dqMsgs[1].ID, ReservationID: "not-a-reservation"},
+	}
+	deleted, err := cl.DeleteReservedBatch(ctx, token, projID, qName, toDelete)
+	if err != nil {
+		return fmt.Errorf("got error on batch delete [%s]", err)
+	}
+	if len(deleted.Results) != 2 {
+		return fmt.Errorf("batch delete returned [%d] results, expected 2", len(deleted.Results))
+	}
+	if deleted.Results[0].ID != dqMsgs[0].ID || deleted.Results[0].Err != nil {
+		return fmt.Errorf("first batch delete result was %+v, expected message [%s] to be deleted", deleted.Results[0], dqMsgs[0].ID)
+	}
+	if deleted.Results[1].ID != dqMsgs[1].ID || deleted.Results[1].Err != ErrNoSuchReservation {
+		return fmt.Errorf("second batch delete result was %+v, expected ErrNoSuchReservation", deleted.Results[1])
+	}
+	return nil
+}
+
+func qDeleteReservedBatchEmpty(cl Client) error {
+	ctx := context.Background()
+	newMsgs := []NewMessage{
+		{Body: "1", Delay: 0, PushHeaders: make(map[string]string)},
+		{Body: "2", Delay: 0, PushHeaders: make(map[string]string)},
+	}
+	if _, err := cl.Enqueue(ctx, token, projID, qName, newMsgs); err != nil {
+		return fmt.Errorf("got error on enqueue [%s]", err)
+	}
+	for _, msgs := range [][]ReservedMessage{nil, {}} {
+		deleted, err := cl.DeleteReservedBatch(ctx, token, projID, qName, msgs)
+		if err != nil {
+			return fmt.Errorf("got error on batch delete of %#v [%s]", msgs, err)
+		}
+		if len(deleted.Results) != 0 {
+			return fmt.Errorf("batch delete of %#v returned [%d] results, expected 0", msgs, len(deleted.Results))
+		}
+		peeked, err := cl.Peek(ctx, token, projID, qName, 10)
+		if err != nil {
+			return fmt.Errorf("got error on peek [%s]", err)
+		}
+		if len(peeked) != 2 {
+			return fmt.Errorf("peeked [%d] messages after batch delete of %#v, expected 2", len(peeked), msgs)
+		}
+	}
+	return nil
+}
+
+func qClearQueue(cl Client) error {
+	ctx := context.Background()
+	newMsgs := []NewMessage{
+		{Body: "1", Delay: 0, PushHeaders: make(map[string]string)},
+		{Body: "2", Delay: 0, PushHeaders: make(map[string]string)},
+	}
+	if _, err := cl.Enqueue(ctx, token, projID, qName, newMsgs); err != nil {
+		return fmt.Errorf("got error on enqueue [%s]", err)
+	}
+	cleared, err := cl.ClearQueue(ctx, token, projID, qName)
+	if err != nil {
+		return fmt.Errorf("got error on clear [%s]", err)
+	}
+	if len(cleared.Msg) <= 0 {
+		return fmt.Errorf("ClearQueue returned an empty message")
+	}
+	info, err := cl.GetQueue(ctx, token, projID, qName)
+	if err != nil {
+		return fmt.Errorf("got error on get after clear [%s]", err)
+	}
+	if info.Size != 0 {
+		return fmt.Errorf("queue size was [%d] after clear, expected 0", info.Size)
+	}
+	return nil
+}
+
+func qGetMessage(cl Client) error {
+	ctx := context.Background()
+	newMsgs := []NewMessage{{Body: "123", Delay: 0, PushHeaders: make(map[string]string)}}
+	enq, err := cl.Enqueue(ctx, token, projID, qName, newMsgs)
+	if err != nil {
+		return fmt.Errorf("got error on enqueue [%s]", err)
+	}
+	dqMsgs, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(1), false)
+	if err != nil {
+		return fmt.Errorf("got error on dequeue [%s]", err)
+	}
+	if len(dqMsgs) != 1 {
+		return fmt.Errorf("dequeued [%d] messages, expected 1", len(dqMsgs))
+	}
+	if len(enq.IDs) != 1 || enq.IDs[0] != dqMsgs[0].ID {
+		return fmt.Errorf("enqueued message IDs %v don't match dequeued message ID [%s]", enq.IDs, dqMsgs[0].ID)
+	}
+	msg, err := cl.GetMessage(ctx, token, projID, qName, dqMsgs[0].ID)
+	if err != nil {
+		return fmt.Errorf("got error getting reserved message [%s]", err)
+	}
+	if msg.Body != newMsgs[0].Body || msg.ReservedCount != 1 || msg.ReservationID != dqMsgs[0].ReservationID {
+		return fmt.Errorf("got reserved message %+v, expected body [%s], 1 reservation and reservation ID [%s]", msg, newMsgs[0].Body, dqMsgs[0].ReservationID)
+	}
+	if _, err := cl.Release(ctx, token, projID, qName, dqMsgs[0].ID, dqMsgs[0].ReservationID, 0); err != nil {
+		return fmt.Errorf("got error on release [%s]", err)
+	}
+	msg, err = cl.GetMessage(ctx, token, projID, qName, dqMsgs[0].ID)
+	if err != nil {
+		return fmt.Errorf("got error getting released message [%s]", err)
+	}
+	if msg.ReservationID != "" {
+		return fmt.Errorf("released message had reservation ID [%s]", msg.ReservationID)
+	}
+	return nil
+}
+
+// subscriberNames returns the names of the subscribers on the push queue qName
+func subscriberNames(cl Client, qName string) ([]string, error) {
+	info, err := cl.GetQueue(context.Background(), token, projID, qName)
+	if err != nil {
+		return nil, fmt.Errorf("got error getting queue [%s]", err)
+	}
+	if info.Push == nil {
+		return nil, fmt.Errorf("queue [%s] has no push config", qName)
+	}
+	var ret []string
+	for _, sub := range info.Push.Subscribers {
+		ret = append(ret, sub.Name)
+	}
+	return ret, nil
+}
+
+func qSubscribers(cl Client) error {
+	ctx := context.Background()
+	conf := QueueConfig{
+		Type: QueueTypeMulticast,
+		Push: &PushConfig{Subscribers: []Subscriber{{Name: "a", URL: "http://localhost:1/a"}}},
+	}
+	if _, err := cl.CreateQueue(ctx, token, projID, qName, conf); err != nil {
+		return fmt.Errorf("got error creating push queue [%s]", err)
+	}
+	expected := []string{"a", "b"}
+	if _, err := cl.AddSubscribers(ctx, token, projID, qName, []Subscriber{{Name: "b", URL: "http://localhost:1/b"}}); err != nil {
+		return fmt.Errorf("got error adding subscribers [%s]", err)
+	}
+	if names, err := subscriberNames(cl, qName); err != nil {
+		return err
+	} else if fmt.Sprint(names) != fmt.Sprint(expected) {
+		return fmt.Errorf("subscribers after add were %v, expected %v", names, expected)
+	}
+	expected = []string{"c", "d"}
+	replacement := []Subscriber{{Name: "c", URL: "http://localhost:1/c"}, {Name: "d", URL: "http://localhost:1/d"}}
+	if _, err := cl.ReplaceSubscribers(ctx, token, projID, qName, replacement); err != nil {
+		return fmt.Errorf("got error replacing subscribers [%s]", err)
+	}
+	if names, err := subscriberNames(cl, qName); err != nil {
+		return err
+	} else if fmt.Sprint(names) != fmt.Sprint(expected) {
+		return fmt.Errorf("subscribers after replace were %v, expected %v", names, expected)
+	}
+	expected = []string{"d"}
+	if _, err := cl.RemoveSubscribers(ctx, token, projID, qName, []string{"c"}); err != nil {
+		return fmt.Errorf("got error removing subscribers [%s]", err)
+	}
+	if names, err := subscriberNames(cl, qName); err != nil {
+		return err
+	} else if fmt.Sprint(names) != fmt.Sprint(expected) {
+		return fmt.Errorf("subscribers after remove were %v, expected %v", names, expected)
+	}
+	if _, err := cl.ReplaceSubscribers(ctx, token, projID, qName, nil); err != ErrNoSubscribers {
+		return fmt.Errorf("replacing with no subscribers returned [%v], expected [%s]", err, ErrNoSubscribers)
+	}
+	if _, err := cl.RemoveSubscribers(ctx, token, projID, qName, []string{"d"}); err != ErrNoSubscribers {
+		return fmt.Errorf("removing the last subscriber returned [%v], expected [%s]", err, ErrNoSubscribers)
+	}
+	info, err := cl.UpdateQueue(ctx, token, projID, qName, QueueConfig{Type: QueueTypePull})
+	if err != nil {
+		return fmt.Errorf("got error changing the push queue to a pull queue [%s]", err)
+	}
+	if info.Type != QueueTypePull || info.Push != nil {
+		return fmt.Errorf("queue changed to pull had type [%s] and push config %+v, expected [%s] and none", info.Type, info.Push, QueueTypePull)
+	}
+	if _, err := cl.AddSubscribers(ctx, token, projID, qName, []Subscriber{{Name: "e", URL: "http://localhost:1/e"}}); err != ErrNotPushQueue {
+		return fmt.Errorf("adding subscribers to a pull queue returned [%v], expected [%s]", err, ErrNotPushQueue)
+	}
+	return nil
+}
+
+func qAlerts(cl Client) error {
+	ctx := context.Background()
+	if _, err := cl.CreateQueue(ctx, token, projID, qName, QueueConfig{}); err != nil {
+		return fmt.Errorf("got error creating queue [%s]", err)
+	}
+	alerts := []Alert{
+		{Type: AlertTypeFixed, Trigger: 10, Direction: AlertDirectionAsc, Queue: "scale-up"},
+		{Type: AlertTypeProgressive, Trigger: 100, Direction: AlertDirectionAsc, Queue: "scale-up"},
+	}
+	if _, err := cl.AddAlerts(ctx, token, projID, qName, alerts); err != nil {
+		return fmt.Errorf("got error adding alerts [%s]", err)
+	}
+	info, err := cl.GetQueue(ctx, token, projID, qName)
+	if err != nil {
+		return fmt.Errorf("got error getting queue [%s]", err)
+	}
+	if len(info.Alerts) != 2 {
+		return fmt.Errorf("queue had [%d] alerts, expected 2", len(info.Alerts))
+	}
+	replacement := []Alert{{Type: AlertTypeFixed, Trigger: 1, Direction: AlertDirectionDesc, Queue: "scale-down"}}
+	if _, err := cl.ReplaceAlerts(ctx, token, projID, qName, replacement); err != nil {
+		return fmt.Errorf("got error replacing alerts [%s]", err)
+	}
+	info, err = cl.GetQueue(ctx, token, projID, qName)
+	if err != nil {
+		return fmt.Errorf("got error getting queue [%s]", err)
+	}
+	if len(info.Alerts) != 1 || info.Alerts[0].Queue != "scale-down" || info.Alerts[0].ID == "" {
+		return fmt.Errorf("queue had alerts %+v after replace, expected 1 alert to scale-down with an ID", info.Alerts)
+	}
+	if _, err := cl.DeleteAlert(ctx, token, projID, qName, info.Alerts[0].ID); err != nil {
+		return fmt.Errorf("got error deleting alert [%s]", err)
+	}
+	if _, err := cl.DeleteAlert(ctx, token, projID, qName, info.Alerts[0].ID); err != ErrNoSuchAlert {
+		return fmt.Errorf("deleting a deleted alert returned [%v], expected [%s]", err, ErrNoSuchAlert)
+	}
+	selfAlert := []Alert{{Type: AlertTypeFixed, Trigger: 1, Queue: qName}}
+	if _, err := cl.AddAlerts(ctx, token, projID, qName, selfAlert); err != ErrInvalidAlert {
+		return fmt.Errorf("adding an alert to the same queue returned [%v], expected [%s]", err, ErrInvalidAlert)
+	}
+	return nil
+}
+
+// webhookClient is a Client that can also post webhooks
+type webhookClient interface {
+	Client
+	WebhookPoster
+}
+
+func qWebhook(cl webhookClient) error {
+	ctx := context.Background()
+	body := `{"event":"push","ref":"refs/heads/master"}`
+	enq, err := cl.PostWebhook(ctx, token, projID, qName, strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("got error posting webhook [%s]", err)
+	}
+	if len(enq.IDs) != 1 {
+		return fmt.Errorf("webhook enqueued [%d] messages, expected 1", len(enq.IDs))
+	}
+	msgs, err := cl.Peek(ctx, token, projID, qName, 1)
+	if err != nil {
+		return fmt.Errorf("got error on peek [%s]", err)
+	}
+	if len(msgs) != 1 || msgs[0].Body != body {
+		return fmt.Errorf("got messages %+v, expected 1 message with body [%s]", msgs, body)
+	}
+	return nil
+}
+
+func qErrors(cl Client) error {
+	ctx := context.Background()
+	if _, err := cl.GetQueue(ctx, token, projID, qName); err != ErrNoSuchQueue {
+		return fmt.Errorf("GetQueue on a missing queue returned error [%v], expected ErrNoSuchQueue", err)
+	}
+	if _, err := cl.CreateQueue(ctx, token, projID, qName, QueueConfig{}); err != nil {
+		return fmt.Errorf("got error on create [%s]", err)
+	}
+	if _, err := cl.CreateQueue(ctx, token, projID, qName, QueueConfig{}); err != ErrQueueExists {
+		return fmt.Errorf("CreateQueue on an existing queue returned error [%v], expected ErrQueueExists", err)
+	}
+	if _, err := cl.GetMessage(ctx, token, projID, qName, "12345"); err != ErrNoSuchMessage {
+		return fmt.Errorf("GetMessage on a missing message returned error [%v], expected ErrNoSuchMessage", err)
+	}
+	if _, err := cl.DeleteReserved(ctx, token, projID, qName, "12345", "not-a-reservation"); err != ErrNoSuchReservation {
+		return fmt.Errorf("DeleteReserved on a missing reservation returned error [%v], expected ErrNoSuchReservation", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package mq
+
+import "time"
+
+// The shared Client tests and test values, exported for the tests in package mq_test. Those
+// run against an mqtest.Server, and mqtest can't be imported from package mq because it
+// imports mq
+const (
+	TestToken  = token
+	TestProjID = projID
+	TestQName  = qName
+)
+
+var (
+	QOperations               = qOperations
+	QLifecycle                = qLifecycle
+	QListQueues               = qListQueues
+	QPeek                     = qPeek
+	QTouchRelease             = qTouchRelease
+	QDeleteReservedBatch      = qDeleteReservedBatch
+	QDeleteReservedBatchEmpty = qDeleteReservedBatchEmpty
+	QClearQueue               = qClearQueue
+	QGetMessage               = qGetMessage
+	QSubscribers              = qSubscribers
+	QAlerts                   = qAlerts
+	QWebhook                  = qWebhook
+	QErrors                   = qErrors
+	QueueHandleOperations     = queueHandleOperations
+)
+
+// Backoff returns p.backoff(attempt)
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	return p.backoff(attempt)
+}
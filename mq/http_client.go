@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/arschles/gorion"
 	"golang.org/x/net/context"
@@ -52,36 +55,123 @@ type HTTPClient struct {
 	scheme     Scheme
 	host       string
 	port       uint16
-	transport  *http.Transport
+	basePath   string
+	userAgent  string
+	canceler   gorion.RequestCanceler
 	client     *http.Client
 	oauthToken string
+	projID     string
+	retry      RetryPolicy
 }
 
-// NewHTTPClient returns a new HTTPClient that talks to the IronMQ v3 API at {scheme}://{host}:{port}
-func NewHTTPClient(scheme Scheme, host string, port uint16) *HTTPClient {
-	transport := &http.Transport{}
-	client := &http.Client{Transport: transport}
+// NewHTTPClient returns a new HTTPClient that talks to the IronMQ v3 API at {scheme}://{host}:{port}.
+// Pass HTTPOptions to customize the underlying HTTP client, transport, TLS configuration and more
+func NewHTTPClient(scheme Scheme, host string, port uint16, opts ...HTTPOption) *HTTPClient {
+	o := &httpOptions{basePath: DefaultBasePath, retry: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(o)
+	}
+	client := o.httpClient()
+	canceler, _ := client.Transport.(gorion.RequestCanceler)
 	return &HTTPClient{
 		scheme:    scheme,
 		host:      host,
 		port:      port,
-		transport: transport,
+		basePath:  o.basePath,
+		userAgent: o.userAgent,
+		canceler:  canceler,
 		client:    client,
+		retry:     o.retry,
+	}
+}
+
+// NewHTTPClientFromConfig returns a new HTTPClient that talks to the IronMQ API at the
+// endpoint in conf. Funcs on the returned client use conf.Token and conf.ProjectID when
+// they're passed an empty token or project ID, so callers don't need to pass either.
+// Use gorion.LoadConfig to get conf from the standard Iron.io configuration sources.
+// Returns ErrInvalidScheme if conf.Scheme isn't a supported scheme
+func NewHTTPClientFromConfig(conf gorion.Config, opts ...HTTPOption) (*HTTPClient, error) {
+	scheme, err := SchemeFromString(conf.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	if conf.APIVersion != "" {
+		opts = append([]HTTPOption{WithBasePath("/" + conf.APIVersion)}, opts...)
+	}
+	ret := NewHTTPClient(scheme, conf.Host, conf.Port, opts...)
+	ret.oauthToken = conf.Token
+	ret.projID = conf.ProjectID
+	return ret, nil
+}
+
+// NewHTTPClientFromEnv returns a new HTTPClient configured with gorion.LoadConfig from
+// the IronMQ environment variables and iron.json files. See NewHTTPClientFromConfig for
+// details on the returned client
+func NewHTTPClientFromEnv(opts ...HTTPOption) (*HTTPClient, error) {
+	conf, err := gorion.LoadConfig(gorion.ProductMQ, gorion.Config{})
+	if err != nil {
+		return nil, err
 	}
+	return NewHTTPClientFromConfig(conf, opts...)
 }
 
-// headers sets json and oauth headers on r
+// newReq creates a request to path in projID with json and oauth headers set. Uses the
+// client's default token and project ID if token or projID are empty
 func (h *HTTPClient) newReq(method, token, projID, path string, body io.Reader) (*http.Request, error) {
-	urlStr := fmt.Sprintf("%s://%s:%d/3/projects/%s/%s", h.scheme, h.host, h.port, projID, path)
+	if token == "" {
+		token = h.oauthToken
+	}
+	if projID == "" {
+		projID = h.projID
+	}
+	urlStr := fmt.Sprintf("%s://%s:%d%s/projects/%s/%s", h.scheme, h.host, h.port, h.basePath, projID, path)
 	req, err := http.NewRequest(method, urlStr, body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "OAuth "+token)
+	if h.userAgent != "" {
+		req.Header.Set("User-Agent", h.userAgent)
+	}
 	return req, nil
 }
 
+// do runs req and decodes the JSON response body into ret, retrying according to h's
+// RetryPolicy. Returns an *APIError if the final response has a non-2xx status code
+func (h *HTTPClient) do(ctx context.Context, req *http.Request, ret interface{}) error {
+	for attempt := 1; ; attempt++ {
+		err := h.doOnce(ctx, req, ret)
+		if err == nil || attempt >= h.retry.MaxAttempts || !retryable(req, err) {
+			return err
+		}
+		if err := h.retry.wait(ctx, attempt, err); err != nil {
+			return err
+		}
+		if req, err = rewind(req); err != nil {
+			return err
+		}
+	}
+}
+
+// doOnce runs req a single time and decodes the JSON response body into ret
+func (h *HTTPClient) doOnce(ctx context.Context, req *http.Request, ret interface{}) error {
+	doFunc := func(resp *http.Response, err error) error {
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return newAPIError(req, resp)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(ret); err != nil {
+			return err
+		}
+		return nil
+	}
+	return gorion.HTTPDo(ctx, h.client, h.canceler, req, doFunc)
+}
+
 type enqueueReq struct {
 	Messages []NewMessage `json:"messages"`
 }
@@ -98,17 +188,22 @@ func (h *HTTPClient) Enqueue(ctx context.Context, token, projID, qName string, m
 		return nil, err
 	}
 	ret := new(Enqueued)
-	doFunc := func(resp *http.Response, err error) error {
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-		if err := json.NewDecoder(resp.Body).Decode(ret); err != nil {
-			return err
-		}
-		return nil
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, err
 	}
-	if err := gorion.HTTPDo(ctx, h.client, h.transport, req, doFunc); err != nil {
+	return ret, nil
+}
+
+// PostWebhook is the WebhookPoster implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#post-messages-via-webhook).
+// body is streamed to IronMQ, so the request isn't retried
+func (h *HTTPClient) PostWebhook(ctx context.Context, token, projID, qName string, body io.Reader) (*Enqueued, error) {
+	req, err := h.newReq("POST", token, projID, fmt.Sprintf("queues/%s/webhook", qName), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	ret := new(Enqueued)
+	if err := h.doOnce(ctx, req, ret); err != nil {
 		return nil, err
 	}
 	return ret, nil
@@ -143,17 +238,7 @@ func (h *HTTPClient) Dequeue(ctx context.Context, token, projID, qName string, n
 		return nil, err
 	}
 	ret := new(dequeueResp)
-	doFunc := func(resp *http.Response, err error) error {
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-		if err := json.NewDecoder(resp.Body).Decode(ret); err != nil {
-			return err
-		}
-		return nil
-	}
-	if err := gorion.HTTPDo(ctx, h.client, h.transport, req, doFunc); err != nil {
+	if err := h.do(ctx, req, ret); err != nil {
 		return nil, err
 	}
 	return ret.Messages, nil
@@ -164,28 +249,387 @@ type deleteReservedReq struct {
 }
 
 // DeleteReserved is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#delete-message)
-func (h *HTTPClient) DeleteReserved(ctx context.Context, token, projID, qName string, messageID int, reservationID string) (*Deleted, error) {
+func (h *HTTPClient) DeleteReserved(ctx context.Context, token, projID, qName string, messageID string, reservationID string) (*Deleted, error) {
 	body := &bytes.Buffer{}
 	if err := json.NewEncoder(body).Encode(deleteReservedReq{ReservationID: reservationID}); err != nil {
 		return nil, err
 	}
-	req, err := h.newReq("DELETE", token, projID, fmt.Sprintf("queues/%s/messages/%d", qName, messageID), body)
+	req, err := h.newReq("DELETE", token, projID, fmt.Sprintf("queues/%s/messages/%s", qName, messageID), body)
 	if err != nil {
 		return nil, err
 	}
 	ret := new(Deleted)
-	doFunc := func(resp *http.Response, err error) error {
-		if err != nil {
-			return err
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, messageErr(err)
+	}
+	return ret, nil
+}
+
+type queueReq struct {
+	Queue QueueConfig `json:"queue"`
+}
+
+type queueResp struct {
+	Queue QueueInfo `json:"queue"`
+}
+
+// putQueue sends conf to the queue endpoint with the given method and returns the resulting queue
+func (h *HTTPClient) putQueue(ctx context.Context, method, token, projID, qName string, conf QueueConfig) (*QueueInfo, error) {
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+	body := &bytes.Buffer{}
+	if err := json.NewEncoder(body).Encode(queueReq{Queue: conf}); err != nil {
+		return nil, err
+	}
+	req, err := h.newReq(method, token, projID, fmt.Sprintf("queues/%s", qName), body)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(queueResp)
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, queueErr(err)
+	}
+	return &ret.Queue, nil
+}
+
+// CreateQueue is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#create-queue)
+func (h *HTTPClient) CreateQueue(ctx context.Context, token, projID, qName string, conf QueueConfig) (*QueueInfo, error) {
+	return h.putQueue(ctx, "PUT", token, projID, qName, conf)
+}
+
+// GetQueue is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#get-queue-info)
+func (h *HTTPClient) GetQueue(ctx context.Context, token, projID, qName string) (*QueueInfo, error) {
+	req, err := h.newReq("GET", token, projID, fmt.Sprintf("queues/%s", qName), nil)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(queueResp)
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, queueErr(err)
+	}
+	return &ret.Queue, nil
+}
+
+// UpdateQueue is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#update-queue)
+func (h *HTTPClient) UpdateQueue(ctx context.Context, token, projID, qName string, conf QueueConfig) (*QueueInfo, error) {
+	return h.putQueue(ctx, "PATCH", token, projID, qName, conf)
+}
+
+// DeleteQueue is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#delete-queue)
+func (h *HTTPClient) DeleteQueue(ctx context.Context, token, projID, qName string) (*Deleted, error) {
+	req, err := h.newReq("DELETE", token, projID, fmt.Sprintf("queues/%s", qName), nil)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(Deleted)
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, queueErr(err)
+	}
+	return ret, nil
+}
+
+type listQueuesResp struct {
+	Queues []QueueInfo `json:"queues"`
+}
+
+// ListQueues is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#list-queues)
+func (h *HTTPClient) ListQueues(ctx context.Context, token, projID, prefix, previous string, perPage int) ([]QueueInfo, error) {
+	if perPage < 0 || perPage > MaxPerPage {
+		return nil, ErrPerPageOutOfRange
+	}
+	query := url.Values{}
+	if perPage > 0 {
+		query.Set("per_page", strconv.Itoa(perPage))
+	}
+	if previous != "" {
+		query.Set("previous", previous)
+	}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	path := "queues"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	req, err := h.newReq("GET", token, projID, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(listQueuesResp)
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, err
+	}
+	for i := range ret.Queues {
+		ret.Queues[i].ProjectID = projID
+	}
+	return ret.Queues, nil
+}
+
+type peekResp struct {
+	Messages []Message `json:"messages"`
+}
+
+// Peek is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#peek-messages)
+func (h *HTTPClient) Peek(ctx context.Context, token, projID, qName string, num int) ([]Message, error) {
+	path := fmt.Sprintf("queues/%s/messages", qName)
+	if num > 0 {
+		path += "?n=" + strconv.Itoa(num)
+	}
+	req, err := h.newReq("GET", token, projID, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(peekResp)
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, err
+	}
+	return ret.Messages, nil
+}
+
+type touchReq struct {
+	ReservationID string `json:"reservation_id"`
+	Timeout       int    `json:"timeout,omitempty"`
+}
+
+// Touch is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#touch-message)
+func (h *HTTPClient) Touch(ctx context.Context, token, projID, qName string, messageID string, reservationID string, timeout Timeout) (*Touched, error) {
+	if timeout != 0 && !timeoutInRange(timeout) {
+		return nil, ErrTimeoutOutOfRange
+	}
+	body := &bytes.Buffer{}
+	if err := json.NewEncoder(body).Encode(touchReq{ReservationID: reservationID, Timeout: int(timeout)}); err != nil {
+		return nil, err
+	}
+	req, err := h.newReq("POST", token, projID, fmt.Sprintf("queues/%s/messages/%s/touch", qName, messageID), body)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(Touched)
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, messageErr(err)
+	}
+	return ret, nil
+}
+
+type releaseReq struct {
+	ReservationID string `json:"reservation_id"`
+	Delay         uint32 `json:"delay"`
+}
+
+// Release is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#release-message)
+func (h *HTTPClient) Release(ctx context.Context, token, projID, qName string, messageID string, reservationID string, delay uint32) (*Released, error) {
+	if delay > MaxDelay {
+		return nil, ErrDelayOutOfRange
+	}
+	body := &bytes.Buffer{}
+	if err := json.NewEncoder(body).Encode(releaseReq{ReservationID: reservationID, Delay: delay}); err != nil {
+		return nil, err
+	}
+	req, err := h.newReq("POST", token, projID, fmt.Sprintf("queues/%s/messages/%s/release", qName, messageID), body)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(Released)
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, messageErr(err)
+	}
+	return ret, nil
+}
+
+type deleteReservedBatchReq struct {
+	IDs []ReservedMessage `json:"ids"`
+}
+
+// DeleteReservedBatch is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#delete-messages)
+func (h *HTTPClient) DeleteReservedBatch(ctx context.Context, token, projID, qName string, msgs []ReservedMessage) (*DeletedBatch, error) {
+	// IronMQ clears the whole queue when a delete request has no IDs
+	if len(msgs) == 0 {
+		return &DeletedBatch{Msg: "Deleted", Results: []DeleteResult{}}, nil
+	}
+	body := &bytes.Buffer{}
+	if err := json.NewEncoder(body).Encode(deleteReservedBatchReq{IDs: msgs}); err != nil {
+		return nil, err
+	}
+	req, err := h.newReq("DELETE", token, projID, fmt.Sprintf("queues/%s/messages", qName), body)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(DeletedBatch)
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, err
+	}
+	for i := range ret.Results {
+		ret.Results[i].Err = deleteResultErr(ret.Results[i].Msg)
+	}
+	return ret, nil
+}
+
+// deleteResultErr converts the msg in a single delete result from IronMQ to an error.
+// Returns nil if msg is "Deleted", ErrNoSuchReservation or ErrNoSuchMessage if msg is
+// exactly the error's text or IronMQ's own message for it, as for APIError, and an error
+// with msg as its text otherwise
+func deleteResultErr(msg string) error {
+	if strings.EqualFold(msg, "deleted") {
+		return nil
+	}
+	if sentinel := sentinelErr(http.StatusNotFound, msg); sentinel == ErrNoSuchReservation || sentinel == ErrNoSuchMessage {
+		return sentinel
+	}
+	return errors.New(msg)
+}
+
+// ClearQueue is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#clear-messages)
+func (h *HTTPClient) ClearQueue(ctx context.Context, token, projID, qName string) (*Cleared, error) {
+	req, err := h.newReq("DELETE", token, projID, fmt.Sprintf("queues/%s/messages", qName), strings.NewReader("{}"))
+	if err != nil {
+		return nil, err
+	}
+	ret := new(Cleared)
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, queueErr(err)
+	}
+	return ret, nil
+}
+
+type getMessageResp struct {
+	Message Message `json:"message"`
+}
+
+// GetMessage is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#get-message-by-id)
+func (h *HTTPClient) GetMessage(ctx context.Context, token, projID, qName string, messageID string) (*Message, error) {
+	req, err := h.newReq("GET", token, projID, fmt.Sprintf("queues/%s/messages/%s", qName, messageID), nil)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(getMessageResp)
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, messageErr(err)
+	}
+	return &ret.Message, nil
+}
+
+type pushStatusesResp struct {
+	Subscribers []PushStatus `json:"subscribers"`
+}
+
+// GetPushStatuses is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#get-push-statuses-for-a-message)
+func (h *HTTPClient) GetPushStatuses(ctx context.Context, token, projID, qName string, messageID string) ([]PushStatus, error) {
+	req, err := h.newReq("GET", token, projID, fmt.Sprintf("queues/%s/messages/%s/subscribers", qName, messageID), nil)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(pushStatusesResp)
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, messageErr(err)
+	}
+	return ret.Subscribers, nil
+}
+
+type subscribersReq struct {
+	Subscribers []Subscriber `json:"subscribers"`
+}
+
+// updateSubscribers sends subs to the subscribers endpoint of qName with the given method
+func (h *HTTPClient) updateSubscribers(ctx context.Context, method, token, projID, qName string, subs []Subscriber) (*Updated, error) {
+	body := &bytes.Buffer{}
+	if err := json.NewEncoder(body).Encode(subscribersReq{Subscribers: subs}); err != nil {
+		return nil, err
+	}
+	req, err := h.newReq(method, token, projID, fmt.Sprintf("queues/%s/subscribers", qName), body)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(Updated)
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, queueErr(err)
+	}
+	return ret, nil
+}
+
+type alertsReq struct {
+	Alerts []Alert `json:"alerts"`
+}
+
+// updateAlerts sends alerts to the alerts endpoint of qName with the given method
+func (h *HTTPClient) updateAlerts(ctx context.Context, method, token, projID, qName string, alerts []Alert) (*Updated, error) {
+	for _, alert := range alerts {
+		if err := alert.validate(); err != nil {
+			return nil, err
 		}
-		defer resp.Body.Close()
-		if err := json.NewDecoder(resp.Body).Decode(ret); err != nil {
-			return err
+		if alert.Queue == qName {
+			return nil, ErrInvalidAlert
 		}
-		return nil
 	}
-	if err := gorion.HTTPDo(ctx, h.client, h.transport, req, doFunc); err != nil {
+	body := &bytes.Buffer{}
+	if err := json.NewEncoder(body).Encode(alertsReq{Alerts: alerts}); err != nil {
+		return nil, err
+	}
+	req, err := h.newReq(method, token, projID, fmt.Sprintf("queues/%s/alerts", qName), body)
+	if err != nil {
 		return nil, err
 	}
+	ret := new(Updated)
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, queueErr(err)
+	}
 	return ret, nil
 }
+
+// AddAlerts is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#add-alerts-to-a-queue)
+func (h *HTTPClient) AddAlerts(ctx context.Context, token, projID, qName string, alerts []Alert) (*Updated, error) {
+	return h.updateAlerts(ctx, "POST", token, projID, qName, alerts)
+}
+
+// ReplaceAlerts is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#replace-alerts-on-a-queue)
+func (h *HTTPClient) ReplaceAlerts(ctx context.Context, token, projID, qName string, alerts []Alert) (*Updated, error) {
+	if alerts == nil {
+		alerts = []Alert{}
+	}
+	return h.updateAlerts(ctx, "PUT", token, projID, qName, alerts)
+}
+
+// DeleteAlert is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#remove-alert-from-a-queue-by-id)
+func (h *HTTPClient) DeleteAlert(ctx context.Context, token, projID, qName, alertID string) (*Deleted, error) {
+	req, err := h.newReq("DELETE", token, projID, fmt.Sprintf("queues/%s/alerts/%s", qName, alertID), nil)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(Deleted)
+	if err := h.do(ctx, req, ret); err != nil {
+		return nil, queueErr(err)
+	}
+	return ret, nil
+}
+
+// AddSubscribers is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#add-subscribers-to-a-queue)
+func (h *HTTPClient) AddSubscribers(ctx context.Context, token, projID, qName string, subs []Subscriber) (*Updated, error) {
+	for _, sub := range subs {
+		if err := sub.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return h.updateSubscribers(ctx, "POST", token, projID, qName, subs)
+}
+
+// ReplaceSubscribers is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#replace-subscribers-on-a-queue)
+func (h *HTTPClient) ReplaceSubscribers(ctx context.Context, token, projID, qName string, subs []Subscriber) (*Updated, error) {
+	if len(subs) == 0 {
+		return nil, ErrNoSubscribers
+	}
+	for _, sub := range subs {
+		if err := sub.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return h.updateSubscribers(ctx, "PUT", token, projID, qName, subs)
+}
+
+// RemoveSubscribers is the client implementation for the IronMQ v3 API (http://dev.iron.io/mq/3/reference/api/#remove-subscribers-from-a-queue)
+func (h *HTTPClient) RemoveSubscribers(ctx context.Context, token, projID, qName string, names []string) (*Updated, error) {
+	subs := make([]Subscriber, len(names))
+	for i, name := range names {
+		subs[i] = Subscriber{Name: name}
+	}
+	return h.updateSubscribers(ctx, "DELETE", token, projID, qName, subs)
+}
@@ -1,4 +1,4 @@
-package mq
+package mq_test
 
 import (
 	"encoding/json"
@@ -7,131 +7,265 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/arschles/assert"
+	"github.com/arschles/gorion"
+	"github.com/arschles/gorion/mq"
+	"github.com/arschles/gorion/mq/mqtest"
 	"golang.org/x/net/context"
 	"github.com/arschles/testsrv"
 	"github.com/gorilla/mux"
 )
 
+const (
+	token  = mq.TestToken
+	projID = mq.TestProjID
+	qName  = mq.TestQName
+)
+
 var (
 	bgCtx = context.Background()
 )
 
-type qServer struct {
-	mem Client
+// newTestHTTPClient returns an HTTPClient that talks to srv
+func newTestHTTPClient(t *testing.T, srv *testsrv.Server, opts ...mq.HTTPOption) *mq.HTTPClient {
+	urlStrSplit := strings.Split(strings.TrimPrefix(srv.URLStr(), "http://"), ":")
+	assert.Equal(t, 2, len(urlStrSplit), "number of elements in the URL string")
+	host := urlStrSplit[0]
+	port, err := strconv.Atoi(urlStrSplit[1])
+	assert.NoErr(t, err)
+	if port > 65535 {
+		t.Fatalf("port [%d] not a uint16", port)
+	}
+	return mq.NewHTTPClient(mq.SchemeHTTP, host, uint16(port), opts...)
 }
 
-func (q *qServer) enqueueHandler() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		qName, ok := mux.Vars(r)["queue_name"]
-		if !ok {
-			http.Error(w, "missing queue name", http.StatusBadRequest)
-			return
-		}
-		defer r.Body.Close()
-		req := new(enqueueReq)
-		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
-			http.Error(w, fmt.Sprintf("invalid json [%s]", err), http.StatusBadRequest)
-			return
-		}
+// runHTTP runs test against an HTTPClient that talks to a new mqtest.Server
+func runHTTP(t *testing.T, test func(mq.Client) error) {
+	srv := mqtest.NewServer(token, projID)
+	defer srv.Close()
+	assert.NoErr(t, test(srv.Client()))
+}
 
-		res, err := q.mem.Enqueue(bgCtx, token, projID, qName, req.Messages)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("error enqueueing [%s]", err), http.StatusInternalServerError)
-			return
-		}
-		if err := json.NewEncoder(w).Encode(res); err != nil {
-			http.Error(w, fmt.Sprintf("error encoding enqueue response json [%s]", err), http.StatusInternalServerError)
-			return
-		}
-	})
+func TestHTTPQueueOperations(t *testing.T) {
+	runHTTP(t, mq.QOperations)
 }
 
-func (q *qServer) dequeueHandler() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		qName, ok := mux.Vars(r)["queue_name"]
-		if !ok {
-			http.Error(w, "missing queue name", http.StatusBadRequest)
-			return
-		}
-		defer r.Body.Close()
-		req := new(dequeueReq)
-		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
-			http.Error(w, fmt.Sprintf("invalid json [%s]", err), http.StatusBadRequest)
-			return
-		}
+func TestHTTPQueueLifecycle(t *testing.T) {
+	runHTTP(t, mq.QLifecycle)
+}
 
-		msgs, err := q.mem.Dequeue(bgCtx, token, projID, qName, req.Num, Timeout(req.Timeout), Wait(req.Wait), false)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("dequeue error [%s]", err), http.StatusInternalServerError)
-			return
-		}
-		if err := json.NewEncoder(w).Encode(dequeueResp{Messages: msgs}); err != nil {
-			http.Error(w, fmt.Sprintf("error encoding dequeue response json [%s]", err), http.StatusInternalServerError)
-			return
-		}
-	})
+func TestHTTPListQueues(t *testing.T) {
+	runHTTP(t, mq.QListQueues)
 }
 
-func (q *qServer) deleteReservedHandler() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		qName, ok := mux.Vars(r)["queue_name"]
-		if !ok {
-			http.Error(w, "missing queue name", http.StatusBadRequest)
-			return
-		}
-		msgIDStr, ok := mux.Vars(r)["message_id"]
-		if !ok {
-			http.Error(w, "missing message ID", http.StatusBadRequest)
-			return
-		}
-		msgID, err := strconv.Atoi(msgIDStr)
-		if err != nil {
-			http.Error(w, "message ID must be an int", http.StatusBadRequest)
-			return
-		}
-		defer r.Body.Close()
-		req := new(deleteReservedReq)
-		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
-			http.Error(w, fmt.Sprintf("invalid json [%s]", err), http.StatusBadRequest)
+func TestHTTPPeek(t *testing.T) {
+	runHTTP(t, mq.QPeek)
+}
+
+func TestHTTPTouchRelease(t *testing.T) {
+	runHTTP(t, mq.QTouchRelease)
+}
+
+func TestHTTPDeleteReservedBatch(t *testing.T) {
+	runHTTP(t, mq.QDeleteReservedBatch)
+}
+
+func TestHTTPDeleteReservedBatchEmpty(t *testing.T) {
+	runHTTP(t, mq.QDeleteReservedBatchEmpty)
+}
+
+func TestHTTPClearQueue(t *testing.T) {
+	runHTTP(t, mq.QClearQueue)
+}
+
+func TestHTTPGetMessage(t *testing.T) {
+	runHTTP(t, mq.QGetMessage)
+}
+
+func TestHTTPSubscribers(t *testing.T) {
+	runHTTP(t, mq.QSubscribers)
+}
+
+func TestHTTPGetPushStatuses(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/3/projects/{project_id}/queues/{queue_name}/messages/{message_id}/subscribers", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["message_id"] != "123" {
+			http.Error(w, `{"msg":"Message not found"}`, http.StatusNotFound)
 			return
 		}
-		ret, err := q.mem.DeleteReserved(bgCtx, token, projID, qName, msgID, req.ReservationID)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("error deleting reserved msg [%s]", err), http.StatusInternalServerError)
-			return
+		fmt.Fprint(w, `{"subscribers":[{"subscriber_name":"a","status_code":200,"tries":1,"msg":"delivered"}]}`)
+	}).Methods("GET")
+	srv := testsrv.StartServer(router)
+	defer srv.Close()
+	cl := newTestHTTPClient(t, srv)
+	statuses, err := cl.GetPushStatuses(bgCtx, token, projID, qName, "123")
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(statuses), "number of push statuses")
+	assert.Equal(t, "a", statuses[0].SubscriberName, "subscriber name")
+	assert.Equal(t, 200, statuses[0].StatusCode, "status code")
+	_, err = cl.GetPushStatuses(bgCtx, token, projID, qName, "456")
+	assert.Err(t, mq.ErrNoSuchMessage, err)
+}
+
+func TestHTTPAlerts(t *testing.T) {
+	runHTTP(t, mq.QAlerts)
+}
+
+func TestHTTPWebhook(t *testing.T) {
+	srv := mqtest.NewServer(token, projID)
+	defer srv.Close()
+	assert.NoErr(t, mq.QWebhook(srv.Client()))
+}
+
+func TestHTTPErrors(t *testing.T) {
+	runHTTP(t, mq.QErrors)
+}
+
+func TestHTTPSentinelErrors(t *testing.T) {
+	sentinels := []error{
+		mq.ErrTimeoutOutOfRange, mq.ErrWaitOutOfRange, mq.ErrDelayOutOfRange, mq.ErrNoSuchReservation,
+		mq.ErrNoSuchMessage, mq.ErrNoSuchQueue, mq.ErrQueueExists, mq.ErrExpirationOutOfRange,
+		mq.ErrInvalidQueueType, mq.ErrNoSubscribers, mq.ErrInvalidSubscriber, mq.ErrNotPushQueue,
+		mq.ErrInvalidAlert, mq.ErrNoSuchAlert, mq.ErrPerPageOutOfRange,
+	}
+	srv := mqtest.NewServer(token, projID)
+	defer srv.Close()
+	cl := srv.Client()
+	calls := map[string]func() error{
+		"GetQueue": func() error {
+			_, err := cl.GetQueue(bgCtx, token, projID, qName)
+			return err
+		},
+		"DeleteReserved": func() error {
+			_, err := cl.DeleteReserved(bgCtx, token, projID, qName, "123", "456")
+			return err
+		},
+		"DeleteAlert": func() error {
+			_, err := cl.DeleteAlert(bgCtx, token, projID, qName, "123")
+			return err
+		},
+		"AddSubscribers": func() error {
+			_, err := cl.AddSubscribers(bgCtx, token, projID, qName, []mq.Subscriber{{Name: "a", URL: "http://localhost:1/a"}})
+			return err
+		},
+	}
+	// each sentinel error that the server returns comes back out of the client as itself
+	for _, sentinel := range sentinels {
+		remove := srv.Mem.InjectFault(mq.Fault{Err: sentinel})
+		for name, call := range calls {
+			err := call()
+			assert.True(t, err == sentinel, "%s returned [%v] when the server returned [%s]", name, err, sentinel)
 		}
-		if err := json.NewEncoder(w).Encode(ret); err != nil {
-			http.Error(w, fmt.Sprintf("error encoding response json [%s]", err), http.StatusInternalServerError)
-			return
+		remove()
+	}
+}
+
+func TestHTTPErrorMessagesMatchExactly(t *testing.T) {
+	hndl := func(w http.ResponseWriter, r *http.Request) {
+		msg := `{"msg":"not a push queue"}`
+		if strings.Contains(r.URL.Path, "/queues/other/") {
+			msg = `{"msg":"this is not a push queue"}`
 		}
-	})
+		http.Error(w, msg, http.StatusBadRequest)
+	}
+	srv := testsrv.StartServer(http.HandlerFunc(hndl))
+	defer srv.Close()
+	cl := newTestHTTPClient(t, srv)
+	subs := []mq.Subscriber{{Name: "a", URL: "http://localhost:1/a"}}
+	_, err := cl.AddSubscribers(bgCtx, token, projID, qName, subs)
+	assert.Err(t, mq.ErrNotPushQueue, err)
+	// a message that only contains a sentinel error's text doesn't mean that error
+	_, err = cl.AddSubscribers(bgCtx, token, projID, "other", subs)
+	_, ok := err.(*mq.APIError)
+	assert.True(t, ok, "returned error [%s] was not an *APIError", err)
 }
 
-func makeQHandler() http.Handler {
-	srv := &qServer{mem: NewMemClient()}
-	r := mux.NewRouter()
-	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		http.Error(w, fmt.Sprintf(`{"msg":"path %s not found"`, r.URL), http.StatusNotFound)
-	})
-	r.Handle("/3/projects/{project_id}/queues/{queue_name}/messages", srv.enqueueHandler()).Methods("POST")
-	r.Handle("/3/projects/{project_id}/queues/{queue_name}/reservations", srv.dequeueHandler()).Methods("POST")
-	r.Handle("/3/projects/{project_id}/queues/{queue_name}/messages/{message_id}", srv.deleteReservedHandler()).Methods("DELETE")
-	return r
+func TestHTTPQueueHandle(t *testing.T) {
+	srv := mqtest.NewServer(token, projID)
+	defer srv.Close()
+	mq.QueueHandleOperations(t, srv.Client().Queue(token, projID, qName))
 }
 
-func TestHTTPQueueOperations(t *testing.T) {
-	srv := testsrv.StartServer(makeQHandler())
+func TestHTTPAPIError(t *testing.T) {
+	hndl := func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"msg":"invalid token"}`, http.StatusInternalServerError)
+	}
+	srv := testsrv.StartServer(http.HandlerFunc(hndl))
 	defer srv.Close()
-	urlStrSplit := strings.Split(strings.TrimPrefix(srv.URLStr(), "http://"), ":")
-	assert.Equal(t, 2, len(urlStrSplit), "number of elements in the URL string")
-	host := urlStrSplit[0]
-	port, err := strconv.Atoi(urlStrSplit[1])
+	cl := newTestHTTPClient(t, srv)
+	_, err := cl.GetQueue(bgCtx, token, projID, qName)
+	apiErr, ok := err.(*mq.APIError)
+	assert.True(t, ok, "returned error [%s] was not an *APIError", err)
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode, "status code")
+	assert.Equal(t, "invalid token", apiErr.Msg, "error message")
+	assert.Equal(t, "GET", apiErr.Method, "method")
+	assert.Equal(t, fmt.Sprintf("/3/projects/%s/queues/%s", projID, qName), apiErr.Path, "path")
+}
+
+func TestHTTPOptions(t *testing.T) {
+	reqCh := make(chan *http.Request, 1)
+	hndl := func(w http.ResponseWriter, r *http.Request) {
+		reqCh <- r
+		json.NewEncoder(w).Encode(map[string]mq.QueueInfo{"queue": {Name: qName}})
+	}
+	srv := testsrv.StartServer(http.HandlerFunc(hndl))
+	defer srv.Close()
+	var roundTrips int
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		roundTrips++
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	cl := newTestHTTPClient(t, srv, mq.WithTransport(rt), mq.WithUserAgent("gorion-test"), mq.WithBasePath("/ironmq/3"))
+	_, err := cl.GetQueue(bgCtx, token, projID, qName)
 	assert.NoErr(t, err)
-	if port > 65535 {
-		t.Fatalf("port [%d] not a uint16", port)
+	r := <-reqCh
+	assert.Equal(t, fmt.Sprintf("/ironmq/3/projects/%s/queues/%s", projID, qName), r.URL.Path, "request path")
+	assert.Equal(t, "gorion-test", r.Header.Get("User-Agent"), "user agent")
+	assert.Equal(t, 1, roundTrips, "number of round trips through the custom transport")
+}
+
+func TestHTTPCustomTransportCancel(t *testing.T) {
+	done := make(chan struct{})
+	hndl := func(http.ResponseWriter, *http.Request) { <-done }
+	srv := testsrv.StartServer(http.HandlerFunc(hndl))
+	defer srv.Close()
+	defer close(done)
+	rt := roundTripperFunc(http.DefaultTransport.RoundTrip)
+	cl := newTestHTTPClient(t, srv, mq.WithHTTPClient(&http.Client{Transport: rt}), mq.WithRetryPolicy(mq.NoRetries))
+	ctx, cancel := context.WithTimeout(bgCtx, 50*time.Millisecond)
+	defer cancel()
+	_, err := cl.GetQueue(ctx, token, projID, qName)
+	assert.Err(t, context.DeadlineExceeded, err)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (r roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+func TestHTTPClientFromConfig(t *testing.T) {
+	reqCh := make(chan *http.Request, 1)
+	hndl := func(w http.ResponseWriter, r *http.Request) {
+		reqCh <- r
+		json.NewEncoder(w).Encode(map[string]mq.QueueInfo{"queue": {Name: qName}})
 	}
-	cl := NewHTTPClient(SchemeHTTP, host, uint16(port))
-	assert.NoErr(t, qOperations(cl))
+	srv := testsrv.StartServer(http.HandlerFunc(hndl))
+	defer srv.Close()
+	hostPort := strings.Split(strings.TrimPrefix(srv.URLStr(), "http://"), ":")
+	port, err := strconv.Atoi(hostPort[1])
+	assert.NoErr(t, err)
+	conf := gorion.Config{Token: token, ProjectID: projID, Scheme: "http", Host: hostPort[0], Port: uint16(port), APIVersion: "3"}
+	cl, err := mq.NewHTTPClientFromConfig(conf)
+	assert.NoErr(t, err)
+	_, err = cl.GetQueue(bgCtx, "", "", qName)
+	assert.NoErr(t, err)
+	r := <-reqCh
+	assert.Equal(t, fmt.Sprintf("/3/projects/%s/queues/%s", projID, qName), r.URL.Path, "request path")
+	assert.Equal(t, "OAuth "+token, r.Header.Get("Authorization"), "authorization header")
+	conf.Scheme = "ftp"
+	_, err = mq.NewHTTPClientFromConfig(conf)
+	assert.Err(t, mq.ErrInvalidScheme, err)
 }
@@ -0,0 +1,107 @@
+package mq
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// DefaultBasePath is the path that NewHTTPClient puts in front of every IronMQ v3 API path
+const DefaultBasePath = "/3"
+
+type httpOptions struct {
+	client    *http.Client
+	transport http.RoundTripper
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	userAgent string
+	basePath  string
+	retry     RetryPolicy
+}
+
+// HTTPOption configures an HTTPClient. Pass HTTPOptions to NewHTTPClient
+type HTTPOption func(*httpOptions)
+
+// WithHTTPClient makes the HTTPClient send requests with a copy of client, so that its
+// timeout, cookie jar and redirect policy apply. The copy's transport is client.Transport
+// unless WithTransport is also passed
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(o *httpOptions) {
+		o.client = client
+	}
+}
+
+// WithTransport makes the HTTPClient send requests with rt. Use it to configure proxies,
+// connection pools and dial timeouts, or to wrap requests with tracing or logging.
+// Requests are still cancelled when their context.Context is, as long as rt honors request
+// contexts, as *http.Transport does
+func WithTransport(rt http.RoundTripper) HTTPOption {
+	return func(o *httpOptions) {
+		o.transport = rt
+	}
+}
+
+// WithTLSConfig makes the HTTPClient use conf for TLS connections. It has no effect if the
+// transport (from WithTransport or WithHTTPClient) isn't an *http.Transport
+func WithTLSConfig(conf *tls.Config) HTTPOption {
+	return func(o *httpOptions) {
+		o.tlsConfig = conf
+	}
+}
+
+// WithTimeout sets the time limit for each request that the HTTPClient makes, including
+// reading the response body. A timeout of 0 means no timeout
+func WithTimeout(timeout time.Duration) HTTPOption {
+	return func(o *httpOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithUserAgent makes the HTTPClient send ua in the User-Agent header of each request
+func WithUserAgent(ua string) HTTPOption {
+	return func(o *httpOptions) {
+		o.userAgent = ua
+	}
+}
+
+// WithBasePath makes the HTTPClient put path in front of every IronMQ v3 API path instead
+// of DefaultBasePath. Use it when IronMQ is behind a proxy that serves it under a sub-path
+func WithBasePath(path string) HTTPOption {
+	return func(o *httpOptions) {
+		o.basePath = path
+	}
+}
+
+// WithRetryPolicy makes the HTTPClient retry requests according to p instead of DefaultRetryPolicy
+func WithRetryPolicy(p RetryPolicy) HTTPOption {
+	return func(o *httpOptions) {
+		o.retry = p
+	}
+}
+
+// httpClient builds the *http.Client that o describes
+func (o *httpOptions) httpClient() *http.Client {
+	client := &http.Client{}
+	if o.client != nil {
+		*client = *o.client
+	}
+	if o.transport != nil {
+		client.Transport = o.transport
+	}
+	if client.Transport == nil {
+		// clone so that proxy settings, dial timeouts and connection pooling match
+		// http.DefaultTransport without sharing (or mutating) it
+		client.Transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	if o.tlsConfig != nil {
+		if t, ok := client.Transport.(*http.Transport); ok {
+			t = t.Clone()
+			t.TLSClientConfig = o.tlsConfig
+			client.Transport = t
+		}
+	}
+	if o.timeout > 0 {
+		client.Timeout = o.timeout
+	}
+	return client
+}
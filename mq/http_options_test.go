@@ -0,0 +1,21 @@
+package mq
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"github.com/arschles/assert"
+)
+
+func TestHTTPOptionsDefaultTransport(t *testing.T) {
+	def := http.DefaultTransport.(*http.Transport)
+	o := &httpOptions{tlsConfig: &tls.Config{ServerName: "mq.example.com"}}
+	tr, ok := o.httpClient().Transport.(*http.Transport)
+	assert.True(t, ok, "default transport was not an *http.Transport")
+	assert.False(t, tr == def, "default transport was http.DefaultTransport, expected a clone")
+	assert.True(t, tr.Proxy != nil, "default transport has no proxy func")
+	assert.Equal(t, def.MaxIdleConns, tr.MaxIdleConns, "max idle conns")
+	assert.Equal(t, "mq.example.com", tr.TLSClientConfig.ServerName, "TLS server name")
+	assert.True(t, def.TLSClientConfig == nil || def.TLSClientConfig.ServerName == "", "http.DefaultTransport's TLS config was modified")
+}
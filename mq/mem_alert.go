@@ -0,0 +1,151 @@
+package mq
+
+import (
+	"encoding/json"
+	"time"
+
+	"code.google.com/p/go-uuid/uuid"
+	"golang.org/x/net/context"
+)
+
+// memAlert is an alert on an in-memory queue
+type memAlert struct {
+	Alert
+	// true if the alert fired less than Snooze seconds ago
+	snoozed bool
+}
+
+// checkAlerts fires the alerts on qName whose triggers were crossed since the last time
+// they were checked. Alerts are checked against the number of messages that are waiting
+// to be dequeued. The alert messages aren't checked against the alerts on their target
+// queues until the next operation on those queues. Must be called with m.lck held
+func (m *MemClient) checkAlerts(projID, qName string) {
+	meta, ok := m.meta[qKey(projID, qName)]
+	if !ok {
+		return
+	}
+	prev := meta.alertSize
+	cur := len(m.queues[qKey(projID, qName)])
+	meta.alertSize = cur
+	for _, alert := range meta.alerts {
+		if alert.snoozed || !alert.fires(prev, cur) {
+			continue
+		}
+		body, err := json.Marshal(AlertMessage{
+			AlertID:        alert.ID,
+			AlertType:      alert.Type,
+			AlertDirection: alert.Direction,
+			AlertTrigger:   alert.Trigger,
+			SourceQueue:    qName,
+			QueueSize:      cur,
+		})
+		if err != nil {
+			continue
+		}
+		m.enqueue(projID, alert.Queue, NewMessage{Body: string(body), PushHeaders: make(map[string]string)})
+		if alert.Snooze > 0 {
+			alert.snoozed = true
+			m.unsnooze(alert)
+		}
+	}
+}
+
+// unsnooze schedules alert to be able to fire again after its snooze period. Must be called
+// with m.lck held
+func (m *MemClient) unsnooze(alert *memAlert) {
+	m.afterFunc(time.Duration(alert.Snooze)*time.Second, func() {
+		m.lck.Lock()
+		defer m.lck.Unlock()
+		alert.snoozed = false
+	})
+}
+
+// alertList returns a copy of the alerts on q
+func (q *memQueue) alertList() []Alert {
+	if len(q.alerts) == 0 {
+		return nil
+	}
+	ret := make([]Alert, len(q.alerts))
+	for i, alert := range q.alerts {
+		ret[i] = alert.Alert
+	}
+	return ret
+}
+
+// AddAlerts is the interface implementation
+func (m *MemClient) AddAlerts(ctx context.Context, token, projID, qName string, alerts []Alert) (*Updated, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpAddAlerts, projID, qName); err != nil {
+		return nil, err
+	}
+	return m.updateAlerts(projID, qName, alerts, false)
+}
+
+// ReplaceAlerts is the interface implementation
+func (m *MemClient) ReplaceAlerts(ctx context.Context, token, projID, qName string, alerts []Alert) (*Updated, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpReplaceAlerts, projID, qName); err != nil {
+		return nil, err
+	}
+	return m.updateAlerts(projID, qName, alerts, true)
+}
+
+// updateAlerts adds alerts to qName, first removing the existing alerts if replace is true
+func (m *MemClient) updateAlerts(projID, qName string, alerts []Alert, replace bool) (*Updated, error) {
+	for _, alert := range alerts {
+		if err := alert.validate(); err != nil {
+			return nil, err
+		}
+		if alert.Queue == qName {
+			return nil, ErrInvalidAlert
+		}
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	key := qKey(projID, qName)
+	if _, ok := m.queues[key]; !ok {
+		return nil, ErrNoSuchQueue
+	}
+	meta := m.queueMeta(key)
+	if replace {
+		meta.alerts = nil
+	}
+	for _, alert := range alerts {
+		if alert.ID == "" {
+			alert.ID = uuid.New()
+		}
+		if alert.Direction == "" {
+			alert.Direction = AlertDirectionAsc
+		}
+		meta.alerts = append(meta.alerts, &memAlert{Alert: alert})
+	}
+	return &Updated{Msg: "Alerts were added."}, nil
+}
+
+// DeleteAlert is the interface implementation
+func (m *MemClient) DeleteAlert(ctx context.Context, token, projID, qName, alertID string) (*Deleted, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpDeleteAlert, projID, qName); err != nil {
+		return nil, err
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	key := qKey(projID, qName)
+	if _, ok := m.queues[key]; !ok {
+		return nil, ErrNoSuchQueue
+	}
+	meta := m.queueMeta(key)
+	for i, alert := range meta.alerts {
+		if alert.ID == alertID {
+			meta.alerts = append(meta.alerts[:i], meta.alerts[i+1:]...)
+			return &Deleted{Msg: "Deleted"}, nil
+		}
+	}
+	return nil, ErrNoSuchAlert
+}
@@ -1,8 +1,12 @@
 package mq
 
 import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"code.google.com/p/go-uuid/uuid"
@@ -11,8 +15,29 @@ import (
 )
 
 type memMsg struct {
-	NewMessage
 	DequeuedMessage
+	// the delay that the message was enqueued or released with
+	Delay uint32
+	// the push headers that the message was enqueued with
+	PushHeaders map[string]string
+	// the qKey of the queue that the message was enqueued onto
+	queue string
+	// the position of the message in the order that the client created messages. Each
+	// duplicated copy of a message gets its own seq
+	seq uint64
+	// whether the message is a copy that a duplicating fault put onto the queue
+	duplicate bool
+}
+
+// memQueue holds the metadata for a single in-memory queue
+type memQueue struct {
+	conf QueueConfig
+	// the number of messages ever enqueued onto the queue
+	total int
+	// the alerts on the queue
+	alerts []*memAlert
+	// the number of messages that were waiting to be dequeued the last time alerts were checked
+	alertSize int
 }
 
 // MemClient is a Client implementation for pure in-memory queues. It's intended
@@ -20,34 +45,74 @@ type memMsg struct {
 type MemClient struct {
 	lck sync.Locker
 	tmr timer.Timer
-	// the counter for message IDs
-	ctr uint64
+	ids IDGenerator
 	// the live queue
 	queues map[string][]memMsg
 	// the map from reservation ID to the message
 	reserved map[string]memMsg
+	// the map from reservation ID to the func that cancels the reservation's release
+	releases map[string]func()
+	// the map from seq to the messages whose delays haven't passed yet. It's keyed by seq
+	// rather than ID because duplicated copies of a message share its ID
+	delayed map[uint64]memMsg
+	// the map from message ID to the number of times the message was dequeued
+	deliveries map[string]int
+	// every delete of a reserved message, in the order they happened
+	deletes []DeleteCall
+	// the seq of the last message that the client created
+	lastSeq uint64
+	// the faults that are injected into calls, in the order they were injected
+	faults []*memFault
+	// the map from qKey to queue metadata
+	meta map[string]*memQueue
+	// the map from qKey to the channel that's closed when the next message goes onto the queue
+	notify map[string]chan struct{}
+	// the map from qKey to the number of Dequeue calls that are waiting for messages on the queue
+	waiting map[string]int
+	// the map from message ID to the delivery record of each message on a push queue
+	pushed map[string]*memPush
+	// the client that pushes messages to push queue subscribers
+	pushClient *http.Client
 }
 
 // NewMemClient returns a purely in-memory Client implementation that can be used
-// for testing. Note that funcs with in-memory client receivers do not pay attention
-// to the context.Context parameters that are passed to them.
-func NewMemClient() *MemClient {
+// for testing. Funcs with in-memory client receivers return ctx.Err() if the
+// context.Context that's passed to them is done before they finish, so tests can
+// exercise cancellation and deadline handling. Pass MemOptions to configure the
+// client.
+func NewMemClient(opts ...MemOption) *MemClient {
 	mtx := sync.Mutex{}
-	return &MemClient{
-		lck:      &mtx,
-		tmr:      timer.NewTimer(),
-		ctr:      0,
-		queues:   make(map[string][]memMsg),
-		reserved: make(map[string]memMsg),
+	ret := &MemClient{
+		lck:        &mtx,
+		tmr:        timer.NewTimer(),
+		ids:        newDefaultIDGenerator(),
+		queues:     make(map[string][]memMsg),
+		reserved:   make(map[string]memMsg),
+		releases:   make(map[string]func()),
+		delayed:    make(map[uint64]memMsg),
+		deliveries: make(map[string]int),
+		meta:       make(map[string]*memQueue),
+		notify:     make(map[string]chan struct{}),
+		waiting:    make(map[string]int),
+		pushed:     make(map[string]*memPush),
+		pushClient: &http.Client{
+			Timeout: pushTimeout,
+		},
+	}
+	for _, opt := range opts {
+		opt(ret)
 	}
+	return ret
 }
 
 func (m *MemClient) newMemMsg(n NewMessage) memMsg {
-	id := atomic.AddUint64(&m.ctr, 1)
+	m.lastSeq++
 	return memMsg{
-		NewMessage: n,
+		Delay:       n.Delay,
+		PushHeaders: n.PushHeaders,
+		seq:         m.lastSeq,
 		DequeuedMessage: DequeuedMessage{
-			ID:            int(id),
+			ID:            m.ids.NewID(),
 			Body:          n.Body,
 			ReservedCount: 0,
 			ReservationID: "",
@@ -55,86 +120,621 @@ func (m *MemClient) newMemMsg(n NewMessage) memMsg {
 	}
 }
 
+// message returns the Message representation of m
+func (m memMsg) message() Message {
+	return Message{
+		ID:            m.ID,
+		Body:          m.Body,
+		ReservedCount: m.ReservedCount,
+		ReservationID: m.ReservationID,
+		PushHeaders:   m.PushHeaders,
+	}
+}
+
 func qKey(projID, qName string) string {
 	return projID + "|" + qName
 }
 
+// queueMeta returns the metadata for the queue at key, creating the queue with the
+// default configuration if it doesn't exist. IronMQ creates queues on the first
+// enqueue, so this mirrors that behavior. Must be called with m.lck held
+func (m *MemClient) queueMeta(key string) *memQueue {
+	if _, ok := m.queues[key]; !ok {
+		m.queues[key] = []memMsg{}
+	}
+	meta, ok := m.meta[key]
+	if !ok {
+		meta = &memQueue{conf: QueueConfig{
+			MessageTimeout:    DefaultMessageTimeout,
+			MessageExpiration: DefaultMessageExpiration,
+			Type:              QueueTypePull,
+		}}
+		m.meta[key] = meta
+	}
+	return meta
+}
+
+// queueInfo returns the QueueInfo for the existing queue at key. Must be called with m.lck held
+func (m *MemClient) queueInfo(projID, qName string) *QueueInfo {
+	key := qKey(projID, qName)
+	meta := m.queueMeta(key)
+	size := len(m.queues[key])
+	for _, msg := range m.reserved {
+		if msg.queue == key {
+			size++
+		}
+	}
+	return &QueueInfo{
+		ProjectID:         projID,
+		Name:              qName,
+		Size:              size,
+		TotalMessages:     meta.total,
+		Type:              meta.conf.Type,
+		MessageTimeout:    meta.conf.MessageTimeout,
+		MessageExpiration: meta.conf.MessageExpiration,
+		Push:              meta.conf.Push.clone(),
+		Alerts:            meta.alertList(),
+	}
+}
+
 // Enqueue is the interface implementation
 func (m *MemClient) Enqueue(ctx context.Context, token, projID, qName string, msgs []NewMessage) (*Enqueued, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	faults, err := m.injectFaults(ctx, OpEnqueue, projID, qName)
+	if err != nil {
+		return nil, err
+	}
+	return m.enqueueAll(projID, qName, msgs, faults), nil
+}
+
+// enqueueAll enqueues msgs onto qName, dropping the messages that faults say to drop
+func (m *MemClient) enqueueAll(projID, qName string, msgs []NewMessage, faults callFaults) *Enqueued {
 	ret := &Enqueued{}
 	m.lck.Lock()
 	defer m.lck.Unlock()
 	for _, msg := range msgs {
-		mmsg := m.newMemMsg(msg)
-		if mmsg.Delay > 0 {
-			go m.deferEnqueue(projID, qName, mmsg)
-		} else {
-			q, _ := m.queues[qKey(projID, qName)]
-			q = append(q, mmsg)
-			m.queues[qKey(projID, qName)] = q
+		if faults.drop() {
+			ret.IDs = append(ret.IDs, m.newMemMsg(msg).ID)
+			continue
 		}
-		ret.IDs = append(ret.IDs, string(mmsg.ID))
+		mmsg := m.enqueue(projID, qName, msg)
+		ret.IDs = append(ret.IDs, mmsg.ID)
 	}
+	m.checkAlerts(projID, qName)
 	ret.Msg = "Messages put on queue"
-	return ret, nil
+	return ret
 }
 
-// Dequeue is the interface implementation
-func (m *MemClient) Dequeue(ctx context.Context, token, projID, qName string, num int, timeout Timeout, wait Wait, delete bool) ([]DequeuedMessage, error) {
-	ch := make(chan memMsg)
+// enqueue enqueues msg onto qName, or starts pushing it if qName is a push queue.
+// Returns the enqueued message. Must be called with m.lck held
+func (m *MemClient) enqueue(projID, qName string, msg NewMessage) memMsg {
+	meta := m.queueMeta(qKey(projID, qName))
+	mmsg := m.newMemMsg(msg)
+	mmsg.queue = qKey(projID, qName)
+	meta.total++
+	if isPushType(meta.conf.Type) {
+		m.startPush(projID, mmsg, meta.conf)
+	} else if mmsg.Delay > 0 {
+		m.deferEnqueue(projID, qName, mmsg)
+	} else {
+		m.appendMsg(projID, qName, mmsg)
+	}
+	return mmsg
+}
 
-	go func() {
+// PostWebhook is the WebhookPoster interface implementation
+func (m *MemClient) PostWebhook(ctx context.Context, token, projID, qName string, body io.Reader) (*Enqueued, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	faults, err := m.injectFaults(ctx, OpPostWebhook, projID, qName)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	return m.enqueueAll(projID, qName, []NewMessage{{Body: string(b), PushHeaders: make(map[string]string)}}, faults), nil
+}
+
+// Dequeue is the interface implementation. It returns as soon as num messages are
+// available, or when wait expires with whatever messages became available before then.
+// Waiting doesn't hold m.lck, so any number of consumers can wait on the same queue
+// while other operations proceed
+func (m *MemClient) Dequeue(ctx context.Context, token, projID, qName string, num int, timeout Timeout, wait Wait, delete bool) ([]DequeuedMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !timeoutInRange(timeout) {
+		return nil, ErrTimeoutOutOfRange
+	}
+	if !waitInRange(wait) {
+		return nil, ErrWaitOutOfRange
+	}
+	faults, err := m.injectFaults(ctx, OpDequeue, projID, qName)
+	if err != nil {
+		return nil, err
+	}
+	if num <= 0 {
+		num = 1
+	}
+	// timeCh is closed when wait expires. A zero wait expires right away, so the dequeue
+	// returns after its first look at the queue
+	timeCh := make(chan struct{})
+	if wait > 0 {
+		stop := m.afterFunc(time.Duration(int(wait))*time.Second, func() { close(timeCh) })
+		defer stop()
+	} else {
+		close(timeCh)
+	}
+	key := qKey(projID, qName)
+	var ret []DequeuedMessage
+	// the number of messages taken from the queue, including dropped ones
+	taken := 0
+	for {
 		m.lck.Lock()
-		defer m.lck.Unlock()
-		timeCh := m.tmr.After(time.Duration(int(wait)) * time.Second)
-		q := m.queues[qKey(projID, qName)]
-		for {
-			select {
-			case <-timeCh:
-				close(ch)
-				return
-			default:
-				if len(q) <= 0 {
-					m.tmr.Sleep(100 * time.Millisecond)
-				} else {
-					msg := q[0]
-					q = q[1:]
-					m.queues[qKey(projID, qName)] = q
-					msg.ReservedCount++
-					msg.ReservationID = uuid.New()
-					if !delete {
-						m.reserved[msg.ReservationID] = msg
-						go m.releaseReservedMsg(projID, qName, msg.ReservationID, timeout)
-					}
-					ch <- msg
-				}
+		for _, msg := range m.take(projID, qName, num-taken, timeout, delete) {
+			taken++
+			if !msg.duplicate && faults.duplicate() {
+				m.duplicateMsg(projID, qName, msg)
 			}
+			if faults.drop() {
+				continue
+			}
+			ret = append(ret, msg.DequeuedMessage)
 		}
-	}()
+		if taken >= num {
+			m.lck.Unlock()
+			return ret, nil
+		}
+		notifyCh := m.notifyCh(key)
+		m.waiting[key]++
+		m.lck.Unlock()
 
-	var ret []DequeuedMessage
-	for r := range ch {
-		ret = append(ret, r.DequeuedMessage)
+		var err error
+		woken := false
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-timeCh:
+		case <-notifyCh:
+			woken = true
+		}
+		m.doneWaiting(key)
+		if err != nil {
+			// like IronMQ, messages that were already reserved go back onto the queue
+			// when their reservations expire
+			return nil, err
+		}
+		if !woken {
+			return ret, nil
+		}
+	}
+}
+
+// doneWaiting records that a Dequeue call stopped waiting for messages on the queue at key
+func (m *MemClient) doneWaiting(key string) {
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	if m.waiting[key]--; m.waiting[key] <= 0 {
+		delete(m.waiting, key)
+	}
+}
+
+// take removes at most num messages from the front of qName and reserves them, or
+// deletes them if delete is true. Returns the removed messages. Must be called with
+// m.lck held
+func (m *MemClient) take(projID, qName string, num int, timeout Timeout, delete bool) []memMsg {
+	key := qKey(projID, qName)
+	var ret []memMsg
+	for len(ret) < num && len(m.queues[key]) > 0 {
+		msg := m.queues[key][0]
+		m.queues[key] = m.queues[key][1:]
+		m.checkAlerts(projID, qName)
+		msg.ReservedCount++
+		m.deliveries[msg.ID]++
+		if delete {
+			msg.ReservationID = uuid.New()
+		} else {
+			msg = m.reserve(projID, qName, msg, timeout)
+		}
+		ret = append(ret, msg)
+	}
+	return ret
+}
+
+// notifyCh returns the channel that's closed the next time a message goes onto the
+// queue at key. Must be called with m.lck held
+func (m *MemClient) notifyCh(key string) <-chan struct{} {
+	ch, ok := m.notify[key]
+	if !ok {
+		ch = make(chan struct{})
+		m.notify[key] = ch
+	}
+	return ch
+}
+
+// appendMsg puts msg at the back of qName and wakes up the consumers that are waiting
+// on qName. Must be called with m.lck held
+func (m *MemClient) appendMsg(projID, qName string, msg memMsg) {
+	key := qKey(projID, qName)
+	m.queues[key] = append(m.queues[key], msg)
+	if ch, ok := m.notify[key]; ok {
+		close(ch)
+		delete(m.notify, key)
+	}
+}
+
+// duplicateMsg puts a copy of msg, which was just taken from qName, back onto the front of
+// qName so that it's delivered again, and wakes up the consumers that are waiting on qName.
+// The copy is marked so that it isn't duplicated itself. Must be called with m.lck held
+func (m *MemClient) duplicateMsg(projID, qName string, msg memMsg) {
+	key := qKey(projID, qName)
+	msg.ReservationID = ""
+	msg.duplicate = true
+	m.lastSeq++
+	msg.seq = m.lastSeq
+	m.queues[key] = append([]memMsg{msg}, m.queues[key]...)
+	if ch, ok := m.notify[key]; ok {
+		close(ch)
+		delete(m.notify, key)
 	}
-	return ret, nil
 }
 
 // DeleteReserved is the interface implementation
-func (m *MemClient) DeleteReserved(ctx context.Context, token, projID, qName string, messageID int, reservationID string) (*Deleted, error) {
+func (m *MemClient) DeleteReserved(ctx context.Context, token, projID, qName string, messageID string, reservationID string) (*Deleted, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpDeleteReserved, projID, qName); err != nil {
+		return nil, err
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	err := m.deleteReserved(projID, qName, messageID, reservationID)
+	m.deletes = append(m.deletes, DeleteCall{ProjectID: projID, QueueName: qName, MessageID: messageID, ReservationID: reservationID, Err: err})
+	if err != nil {
+		return nil, err
+	}
+	return &Deleted{Msg: "deleted"}, nil
+}
+
+// DeleteReservedBatch is the interface implementation
+func (m *MemClient) DeleteReservedBatch(ctx context.Context, token, projID, qName string, msgs []ReservedMessage) (*DeletedBatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpDeleteReservedBatch, projID, qName); err != nil {
+		return nil, err
+	}
 	m.lck.Lock()
 	defer m.lck.Unlock()
+	ret := &DeletedBatch{Msg: "Deleted", Results: make([]DeleteResult, len(msgs))}
+	for i, msg := range msgs {
+		ret.Results[i] = DeleteResult{ID: msg.ID, Msg: "Deleted"}
+		err := m.deleteReserved(projID, qName, msg.ID, msg.ReservationID)
+		m.deletes = append(m.deletes, DeleteCall{ProjectID: projID, QueueName: qName, MessageID: msg.ID, ReservationID: msg.ReservationID, Err: err})
+		if err != nil {
+			ret.Results[i].Msg = err.Error()
+			ret.Results[i].Err = err
+		}
+	}
+	return ret, nil
+}
+
+// deleteReserved permanently deletes the message with the given message ID that's reserved
+// on qName with the given reservation ID. Must be called with m.lck held
+func (m *MemClient) deleteReserved(projID, qName string, messageID string, reservationID string) error {
+	if _, err := m.reservedMsg(projID, qName, messageID, reservationID); err != nil {
+		return err
+	}
+	m.unreserve(reservationID)
+	return nil
+}
+
+// reservedMsg returns the message with the given message ID that's reserved on qName with
+// the given reservation ID. Returns ErrNoSuchReservation if the reservation doesn't exist,
+// expired or was made on another queue, and ErrNoSuchMessage if it's for another message.
+// Must be called with m.lck held
+func (m *MemClient) reservedMsg(projID, qName string, messageID string, reservationID string) (memMsg, error) {
 	msg, ok := m.reserved[reservationID]
-	if !ok {
-		return nil, ErrNoSuchReservation
+	if !ok || msg.queue != qKey(projID, qName) {
+		return memMsg{}, ErrNoSuchReservation
 	}
 	if msg.ID != messageID {
-		return nil, ErrNoSuchMessage
+		return memMsg{}, ErrNoSuchMessage
 	}
-	return &Deleted{Msg: "deleted"}, nil
+	return msg, nil
+}
+
+// CreateQueue is the interface implementation
+func (m *MemClient) CreateQueue(ctx context.Context, token, projID, qName string, conf QueueConfig) (*QueueInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpCreateQueue, projID, qName); err != nil {
+		return nil, err
+	}
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	if _, ok := m.queues[qKey(projID, qName)]; ok {
+		return nil, ErrQueueExists
+	}
+	m.queueMeta(qKey(projID, qName)).conf.update(conf)
+	return m.queueInfo(projID, qName), nil
 }
 
-func (m *MemClient) releaseReservedMsg(projID, qName, resID string, timeout Timeout) {
-	m.tmr.Sleep(time.Duration(int(timeout)) * time.Second)
+// GetQueue is the interface implementation
+func (m *MemClient) GetQueue(ctx context.Context, token, projID, qName string) (*QueueInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpGetQueue, projID, qName); err != nil {
+		return nil, err
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	if _, ok := m.queues[qKey(projID, qName)]; !ok {
+		return nil, ErrNoSuchQueue
+	}
+	return m.queueInfo(projID, qName), nil
+}
+
+// UpdateQueue is the interface implementation
+func (m *MemClient) UpdateQueue(ctx context.Context, token, projID, qName string, conf QueueConfig) (*QueueInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpUpdateQueue, projID, qName); err != nil {
+		return nil, err
+	}
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	if _, ok := m.queues[qKey(projID, qName)]; !ok {
+		return nil, ErrNoSuchQueue
+	}
+	m.queueMeta(qKey(projID, qName)).conf.update(conf)
+	return m.queueInfo(projID, qName), nil
+}
+
+// DeleteQueue is the interface implementation
+func (m *MemClient) DeleteQueue(ctx context.Context, token, projID, qName string) (*Deleted, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpDeleteQueue, projID, qName); err != nil {
+		return nil, err
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	key := qKey(projID, qName)
+	if _, ok := m.queues[key]; !ok {
+		return nil, ErrNoSuchQueue
+	}
+	delete(m.queues, key)
+	delete(m.meta, key)
+	for resID, msg := range m.reserved {
+		if msg.queue == key {
+			m.unreserve(resID)
+		}
+	}
+	m.dropDelayed(key)
+	for id, p := range m.pushed {
+		if p.msg.queue == key {
+			delete(m.pushed, id)
+		}
+	}
+	return &Deleted{Msg: "Deleted"}, nil
+}
+
+// ClearQueue is the interface implementation
+func (m *MemClient) ClearQueue(ctx context.Context, token, projID, qName string) (*Cleared, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpClearQueue, projID, qName); err != nil {
+		return nil, err
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	key := qKey(projID, qName)
+	if _, ok := m.queues[key]; !ok {
+		return nil, ErrNoSuchQueue
+	}
+	m.queues[key] = []memMsg{}
+	for resID, msg := range m.reserved {
+		if msg.queue == key {
+			m.unreserve(resID)
+		}
+	}
+	m.dropDelayed(key)
+	m.checkAlerts(projID, qName)
+	return &Cleared{Msg: "Cleared"}, nil
+}
+
+// ListQueues is the interface implementation
+func (m *MemClient) ListQueues(ctx context.Context, token, projID, prefix, previous string, perPage int) ([]QueueInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpListQueues, projID, ""); err != nil {
+		return nil, err
+	}
+	if perPage < 0 || perPage > MaxPerPage {
+		return nil, ErrPerPageOutOfRange
+	}
+	if perPage == 0 {
+		perPage = DefaultPerPage
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	keyPrefix := qKey(projID, "")
+	var names []string
+	for key := range m.queues {
+		if !strings.HasPrefix(key, keyPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(key, keyPrefix)
+		if strings.HasPrefix(name, prefix) && name > previous {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) > perPage {
+		names = names[:perPage]
+	}
+	ret := make([]QueueInfo, len(names))
+	for i, name := range names {
+		ret[i] = QueueInfo{ProjectID: projID, Name: name}
+	}
+	return ret, nil
+}
+
+// Peek is the interface implementation
+func (m *MemClient) Peek(ctx context.Context, token, projID, qName string, num int) ([]Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpPeek, projID, qName); err != nil {
+		return nil, err
+	}
+	if num <= 0 {
+		num = 1
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	q := m.queues[qKey(projID, qName)]
+	if len(q) > num {
+		q = q[:num]
+	}
+	ret := make([]Message, len(q))
+	for i, msg := range q {
+		ret[i] = msg.message()
+	}
+	return ret, nil
+}
+
+// Touch is the interface implementation
+func (m *MemClient) Touch(ctx context.Context, token, projID, qName string, messageID string, reservationID string, timeout Timeout) (*Touched, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpTouch, projID, qName); err != nil {
+		return nil, err
+	}
+	if timeout != 0 && !timeoutInRange(timeout) {
+		return nil, ErrTimeoutOutOfRange
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	msg, err := m.reservedMsg(projID, qName, messageID, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	if timeout == 0 {
+		timeout = Timeout(m.queueMeta(qKey(projID, qName)).conf.MessageTimeout)
+	}
+	m.unreserve(reservationID)
+	msg = m.reserve(projID, qName, msg, timeout)
+	return &Touched{ReservationID: msg.ReservationID, Msg: "Touched"}, nil
+}
+
+// Release is the interface implementation
+func (m *MemClient) Release(ctx context.Context, token, projID, qName string, messageID string, reservationID string, delay uint32) (*Released, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpRelease, projID, qName); err != nil {
+		return nil, err
+	}
+	if delay > MaxDelay {
+		return nil, ErrDelayOutOfRange
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	msg, err := m.reservedMsg(projID, qName, messageID, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	m.unreserve(reservationID)
+	msg.ReservationID = ""
+	if delay > 0 {
+		msg.Delay = delay
+		m.deferEnqueue(projID, qName, msg)
+	} else {
+		m.appendMsg(projID, qName, msg)
+		m.checkAlerts(projID, qName)
+	}
+	return &Released{Msg: "Released"}, nil
+}
+
+// reserve records msg as reserved under a new reservation ID and schedules it to go
+// back onto the queue after timeout. Returns the reserved message. Must be called
+// with m.lck held
+func (m *MemClient) reserve(projID, qName string, msg memMsg, timeout Timeout) memMsg {
+	msg.ReservationID = uuid.New()
+	msg.queue = qKey(projID, qName)
+	resID := msg.ReservationID
+	m.reserved[resID] = msg
+	m.releases[resID] = m.afterFunc(time.Duration(int(timeout))*time.Second, func() {
+		m.releaseReservedMsg(projID, qName, resID)
+	})
+	return msg
+}
+
+// unreserve removes the reservation with ID resID and cancels its scheduled release.
+// Must be called with m.lck held
+func (m *MemClient) unreserve(resID string) {
+	delete(m.reserved, resID)
+	if cancel, ok := m.releases[resID]; ok {
+		cancel()
+		delete(m.releases, resID)
+	}
+}
+
+// GetMessage is the interface implementation
+func (m *MemClient) GetMessage(ctx context.Context, token, projID, qName string, messageID string) (*Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpGetMessage, projID, qName); err != nil {
+		return nil, err
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	key := qKey(projID, qName)
+	for _, msg := range m.queues[key] {
+		if msg.ID == messageID {
+			ret := msg.message()
+			return &ret, nil
+		}
+	}
+	for _, msg := range m.reserved {
+		if msg.queue == key && msg.ID == messageID {
+			ret := msg.message()
+			return &ret, nil
+		}
+	}
+	if p, ok := m.pushed[messageID]; ok && p.msg.queue == key {
+		ret := p.msg.message()
+		ret.PushStatuses = append([]PushStatus(nil), p.statuses...)
+		return &ret, nil
+	}
+	return nil, ErrNoSuchMessage
+}
+
+// releaseReservedMsg puts the message reserved with resID back onto the queue because its
+// reservation expired
+func (m *MemClient) releaseReservedMsg(projID, qName, resID string) {
 	m.lck.Lock()
 	defer m.lck.Unlock()
 	msg, ok := m.reserved[resID]
@@ -142,12 +742,58 @@ func (m *MemClient) releaseReservedMsg(projID, qName, resID string, timeout Time
 		return
 	}
 	delete(m.reserved, resID)
-	m.queues[qKey(projID, qName)] = append(m.queues[qKey(projID, qName)], msg)
+	delete(m.releases, resID)
+	msg.ReservationID = ""
+	m.appendMsg(projID, qName, msg)
+	m.checkAlerts(projID, qName)
 }
 
+// deferEnqueue schedules msg to go onto the queue after its delay. Must be called with
+// m.lck held
 func (m *MemClient) deferEnqueue(projID, qName string, msg memMsg) {
-	m.tmr.Sleep(time.Duration(int(msg.Delay)) * time.Second)
-	m.lck.Lock()
-	defer m.lck.Unlock()
-	m.queues[qKey(projID, qName)] = append(m.queues[qKey(projID, qName)], msg)
+	msg.queue = qKey(projID, qName)
+	m.delayed[msg.seq] = msg
+	m.afterFunc(time.Duration(int(msg.Delay))*time.Second, func() {
+		m.lck.Lock()
+		defer m.lck.Unlock()
+		// the queue was cleared or deleted during the delay
+		if _, ok := m.delayed[msg.seq]; !ok {
+			return
+		}
+		delete(m.delayed, msg.seq)
+		m.appendMsg(projID, qName, msg)
+		m.checkAlerts(projID, qName)
+	})
+}
+
+// dropDelayed removes the messages whose delays haven't passed yet from the queue at key.
+// Must be called with m.lck held
+func (m *MemClient) dropDelayed(key string) {
+	for seq, msg := range m.delayed {
+		if msg.queue == key {
+			delete(m.delayed, seq)
+		}
+	}
+}
+
+// afterFunc calls fn after d elapses on m's clock, and returns a func that stops the call
+// if it hasn't happened yet. A FakeClock calls fn from the Advance call that moves its time
+// past d, and other clocks call fn in its own goroutine. d is measured from the afterFunc call,
+// not from whenever that goroutine gets to run
+func (m *MemClient) afterFunc(d time.Duration, fn func()) (stop func()) {
+	if clock, ok := m.tmr.(*FakeClock); ok {
+		return clock.afterFunc(d, fn)
+	}
+	ch := m.tmr.After(d)
+	stopCh := make(chan struct{})
+	go func() {
+		select {
+		case <-ch:
+			fn()
+		case <-stopCh:
+		}
+	}()
+	return func() {
+		close(stopCh)
+	}
 }
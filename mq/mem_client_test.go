@@ -1,43 +1,48 @@
 package mq
 
 import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/arschles/assert"
-	"github.com/arschles/synctest"
-	"github.com/pivotal-golang/timer/fake_timer"
+	"github.com/arschles/testsrv"
+	"golang.org/x/net/context"
 )
 
 func TestReleaseReservedMsg(t *testing.T) {
-	fakeTmr := fake_timer.NewFakeTimer(time.Now())
-	lckr := synctest.NewNotifyingLocker()
-	cl := MemClient{tmr: fakeTmr, reserved: make(map[string]memMsg), queues: make(map[string][]memMsg), lck: lckr}
-	msg := cl.newMemMsg(NewMessage{Body: "abc", Delay: 1, PushHeaders: make(map[string]string)})
-	cl.reserved[msg.ReservationID] = msg
-	go cl.releaseReservedMsg(projID, qName, msg.ReservationID, Timeout(2))
-	lockCh := lckr.NotifyLock()
-	fakeTmr.Elapse(3 * time.Second)
-	<-lockCh // wait for the goroutine to get the lock and do its thing
+	clock := NewFakeClock(time.Now())
+	cl := NewMemClient(WithClock(clock))
+	cl.lck.Lock()
+	cl.reserve(projID, qName, cl.newMemMsg(NewMessage{Body: "abc", PushHeaders: make(map[string]string)}), Timeout(2))
+	cl.lck.Unlock()
+	clock.Advance(time.Second)
+	cl.lck.Lock()
+	assert.Equal(t, 0, len(cl.queues[qKey(projID, qName)]), "queue length before the reservation expired")
+	assert.Equal(t, 1, len(cl.reserved), "reserved length before the reservation expired")
+	cl.lck.Unlock()
+	clock.Advance(time.Second)
 	cl.lck.Lock()
 	defer cl.lck.Unlock()
 	assert.Equal(t, 1, len(cl.queues[qKey(projID, qName)]), "queue length")
 	assert.Equal(t, 0, len(cl.reserved), "reserved length")
+	assert.Equal(t, 0, len(cl.releases), "releases length")
 }
 
 func TestDeferEnqueue(t *testing.T) {
-	fakeTmr := fake_timer.NewFakeTimer(time.Now())
-	lckr := synctest.NewNotifyingLocker()
-	cl := MemClient{tmr: fakeTmr, lck: lckr, queues: make(map[string][]memMsg)}
+	clock := NewFakeClock(time.Now())
+	cl := NewMemClient(WithClock(clock))
 	msg := cl.newMemMsg(NewMessage{Body: "abc", Delay: 1, PushHeaders: make(map[string]string)})
-	go cl.deferEnqueue(projID, qName, msg)
 	cl.lck.Lock()
+	cl.deferEnqueue(projID, qName, msg)
 	assert.Equal(t, 0, len(cl.queues[qKey(projID, qName)]), "queue length")
 	assert.Equal(t, 0, len(cl.reserved), "reserved length")
 	cl.lck.Unlock()
-	lockCh := lckr.NotifyLock()
-	fakeTmr.Elapse(2 * time.Second)
-	<-lockCh // wait for the goroutine to get the lock and do its thing
+	clock.Advance(time.Second)
 	cl.lck.Lock()
 	assert.Equal(t, 1, len(cl.queues[qKey(projID, qName)]), "queue length")
 	assert.Equal(t, 0, len(cl.reserved), "reserved length")
@@ -49,3 +54,895 @@ func TestMemQueueOperations(t *testing.T) {
 	err := qOperations(cl)
 	assert.NoErr(t, err)
 }
+
+func TestMemQueueLifecycle(t *testing.T) {
+	cl := NewMemClient()
+	assert.NoErr(t, qLifecycle(cl))
+}
+
+func TestMemQueueLifecycleErrors(t *testing.T) {
+	cl := NewMemClient()
+	ctx := context.Background()
+	_, err := cl.GetQueue(ctx, token, projID, qName)
+	assert.Err(t, ErrNoSuchQueue, err)
+	_, err = cl.UpdateQueue(ctx, token, projID, qName, QueueConfig{})
+	assert.Err(t, ErrNoSuchQueue, err)
+	_, err = cl.DeleteQueue(ctx, token, projID, qName)
+	assert.Err(t, ErrNoSuchQueue, err)
+	_, err = cl.CreateQueue(ctx, token, projID, qName, QueueConfig{MessageTimeout: MaxTimeout + 1})
+	assert.Err(t, ErrTimeoutOutOfRange, err)
+	_, err = cl.CreateQueue(ctx, token, projID, qName, QueueConfig{})
+	assert.NoErr(t, err)
+	_, err = cl.CreateQueue(ctx, token, projID, qName, QueueConfig{})
+	assert.Err(t, ErrQueueExists, err)
+}
+
+func TestMemListQueues(t *testing.T) {
+	cl := NewMemClient()
+	assert.NoErr(t, qListQueues(cl))
+	_, err := cl.CreateQueue(context.Background(), token, "other-proj", "a4", QueueConfig{})
+	assert.NoErr(t, err)
+	queues, err := cl.ListQueues(context.Background(), token, projID, "", "", 0)
+	assert.NoErr(t, err)
+	assert.Equal(t, 4, len(queues), "number of queues in the project")
+	_, err = cl.ListQueues(context.Background(), token, projID, "", "", MaxPerPage+1)
+	assert.Err(t, ErrPerPageOutOfRange, err)
+}
+
+func TestMemPeek(t *testing.T) {
+	cl := NewMemClient()
+	assert.NoErr(t, qPeek(cl))
+	assert.Equal(t, 3, len(cl.queues[qKey(projID, qName)]), "queue length")
+	assert.Equal(t, 0, len(cl.reserved), "reserved length")
+}
+
+func TestMemTouchRelease(t *testing.T) {
+	cl := NewMemClient()
+	assert.NoErr(t, qTouchRelease(cl))
+	assert.Equal(t, 0, len(cl.reserved), "reserved length")
+	assert.Equal(t, 0, len(cl.releases), "releases length")
+}
+
+func TestMemTouchCancelsRelease(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cl := NewMemClient(WithClock(clock))
+	cl.lck.Lock()
+	msg := cl.reserve(projID, qName, cl.newMemMsg(NewMessage{Body: "abc", PushHeaders: make(map[string]string)}), Timeout(30))
+	cl.lck.Unlock()
+	touched, err := cl.Touch(context.Background(), token, projID, qName, msg.ID, msg.ReservationID, Timeout(60))
+	assert.NoErr(t, err)
+	// the original reservation would have expired by now
+	clock.Advance(40 * time.Second)
+	cl.lck.Lock()
+	assert.Equal(t, 0, len(cl.queues[qKey(projID, qName)]), "queue length")
+	_, ok := cl.reserved[touched.ReservationID]
+	assert.True(t, ok, "touched reservation [%s] not found", touched.ReservationID)
+	cl.lck.Unlock()
+	// the touched reservation expires now
+	clock.Advance(30 * time.Second)
+	cl.lck.Lock()
+	defer cl.lck.Unlock()
+	assert.Equal(t, 1, len(cl.queues[qKey(projID, qName)]), "queue length")
+	assert.Equal(t, 0, len(cl.reserved), "reserved length")
+}
+
+func TestMemDeleteReservedBatch(t *testing.T) {
+	cl := NewMemClient()
+	assert.NoErr(t, qDeleteReservedBatch(cl))
+}
+
+func TestMemDeleteReservedBatchEmpty(t *testing.T) {
+	assert.NoErr(t, qDeleteReservedBatchEmpty(NewMemClient()))
+}
+
+func TestMemClearQueue(t *testing.T) {
+	cl := NewMemClient()
+	assert.NoErr(t, qClearQueue(cl))
+	ctx := context.Background()
+	newMsgs := []NewMessage{{Body: "123", Delay: 0, PushHeaders: make(map[string]string)}}
+	_, err := cl.Enqueue(ctx, token, projID, qName, newMsgs)
+	assert.NoErr(t, err)
+	_, err = cl.Enqueue(ctx, token, projID, "other-queue", newMsgs)
+	assert.NoErr(t, err)
+	_, err = cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(1), false)
+	assert.NoErr(t, err)
+	_, err = cl.Dequeue(ctx, token, projID, "other-queue", 1, Timeout(30), Wait(1), false)
+	assert.NoErr(t, err)
+	_, err = cl.ClearQueue(ctx, token, projID, qName)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(cl.reserved), "reserved length")
+	assert.Equal(t, 1, len(cl.releases), "releases length")
+	_, err = cl.ClearQueue(ctx, token, projID, "no-such-queue")
+	assert.Err(t, ErrNoSuchQueue, err)
+}
+
+func TestMemGetMessage(t *testing.T) {
+	cl := NewMemClient()
+	assert.NoErr(t, qGetMessage(cl))
+	_, err := cl.GetMessage(context.Background(), token, projID, qName, "12345")
+	assert.Err(t, ErrNoSuchMessage, err)
+}
+
+func TestMemWebhook(t *testing.T) {
+	assert.NoErr(t, qWebhook(NewMemClient()))
+}
+
+func TestMemErrors(t *testing.T) {
+	cl := NewMemClient()
+	assert.NoErr(t, qErrors(cl))
+}
+
+func TestMemSubscribers(t *testing.T) {
+	cl := NewMemClient()
+	assert.NoErr(t, qSubscribers(cl))
+	ctx := context.Background()
+	subs := []Subscriber{{Name: "a", URL: "http://localhost:1/a"}}
+	_, err := cl.AddSubscribers(ctx, token, projID, "nonexistent", subs)
+	assert.Err(t, ErrNoSuchQueue, err)
+	_, err = cl.CreateQueue(ctx, token, projID, "pull", QueueConfig{})
+	assert.NoErr(t, err)
+	_, err = cl.AddSubscribers(ctx, token, projID, "pull", subs)
+	assert.Err(t, ErrNotPushQueue, err)
+	_, err = cl.AddSubscribers(ctx, token, projID, qName, []Subscriber{{Name: "noURL"}})
+	assert.Err(t, ErrInvalidSubscriber, err)
+}
+
+// eventually calls cond until it returns true, and fails t if it doesn't within a few seconds
+func eventually(t *testing.T, desc string, cond func() bool) {
+	for i := 0; i < 500; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", desc)
+}
+
+func TestMemPush(t *testing.T) {
+	reqs := make(chan *http.Request, 2)
+	bodies := make(chan string, 2)
+	srv := testsrv.StartServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		reqs <- r
+		bodies <- string(b)
+	}))
+	defer srv.Close()
+	cl := NewMemClient()
+	ctx := context.Background()
+	conf := QueueConfig{
+		Type: QueueTypeMulticast,
+		Push: &PushConfig{Subscribers: []Subscriber{
+			{Name: "a", URL: srv.URLStr() + "/a", Headers: map[string]string{"X-Sub": "a", "X-Override": "sub"}},
+			{Name: "b", URL: srv.URLStr() + "/b"},
+		}},
+	}
+	_, err := cl.CreateQueue(ctx, token, projID, qName, conf)
+	assert.NoErr(t, err)
+	_, err = cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: map[string]string{"X-Override": "msg"}}})
+	assert.NoErr(t, err)
+	for i := 0; i < 2; i++ {
+		r := <-reqs
+		assert.Equal(t, "abc", <-bodies, "pushed body")
+		assert.Equal(t, "POST", r.Method, "push method")
+		assert.Equal(t, "msg", r.Header.Get("X-Override"), "overridden header")
+		assert.True(t, r.Header.Get("Iron-Message-Id") != "", "missing message ID header")
+		if r.URL.Path == "/a" {
+			assert.Equal(t, "a", r.Header.Get("X-Sub"), "subscriber header")
+			assert.Equal(t, "a", r.Header.Get("Iron-Subscriber-Name"), "subscriber name header")
+		}
+	}
+	info, err := cl.GetQueue(ctx, token, projID, qName)
+	assert.NoErr(t, err)
+	assert.Equal(t, 0, info.Size, "push queue size")
+	assert.Equal(t, 1, info.TotalMessages, "push queue total messages")
+}
+
+func TestMemRemoveLastSubscriber(t *testing.T) {
+	pushed := make(chan string, 1)
+	srv := testsrv.StartServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushed <- r.Header.Get("Iron-Subscriber-Name")
+	}))
+	defer srv.Close()
+	cl := NewMemClient()
+	ctx := context.Background()
+	conf := QueueConfig{
+		Type: QueueTypeUnicast,
+		Push: &PushConfig{Subscribers: []Subscriber{{Name: "a", URL: srv.URLStr()}}},
+	}
+	_, err := cl.CreateQueue(ctx, token, projID, qName, conf)
+	assert.NoErr(t, err)
+	_, err = cl.RemoveSubscribers(ctx, token, projID, qName, []string{"a"})
+	assert.Err(t, ErrNoSubscribers, err)
+	info, err := cl.GetQueue(ctx, token, projID, qName)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(info.Push.Subscribers), "number of subscribers")
+	// the queue still has a subscriber to push to
+	_, err = cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	assert.Equal(t, "a", <-pushed, "subscriber pushed to")
+	// a delivery with no subscribers does nothing instead of panicking
+	cl.deliverTo(projID, memMsg{}, nil, PushConfig{}, 0)
+}
+
+func TestMemGetPushStatuses(t *testing.T) {
+	ids := make(chan string, 1)
+	srv := testsrv.StartServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids <- r.Header.Get("Iron-Message-Id")
+	}))
+	defer srv.Close()
+	cl := NewMemClient()
+	ctx := context.Background()
+	conf := QueueConfig{
+		Type: QueueTypeMulticast,
+		Push: &PushConfig{Subscribers: []Subscriber{{Name: "a", URL: srv.URLStr()}}},
+	}
+	_, err := cl.CreateQueue(ctx, token, projID, qName, conf)
+	assert.NoErr(t, err)
+	_, err = cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	msgID := <-ids
+
+	var statuses []PushStatus
+	eventually(t, "the push to be recorded", func() bool {
+		statuses, err = cl.GetPushStatuses(ctx, token, projID, qName, msgID)
+		return err == nil && len(statuses) == 1 && statuses[0].Tries == 1
+	})
+	assert.Equal(t, http.StatusOK, statuses[0].StatusCode, "status code")
+	assert.Equal(t, "a", statuses[0].SubscriberName, "subscriber name")
+
+	simulated := []PushStatus{{SubscriberName: "a", URL: srv.URLStr(), StatusCode: http.StatusBadGateway, Tries: 4, Msg: "failed"}}
+	assert.NoErr(t, cl.SetPushStatuses(projID, qName, msgID, simulated))
+	statuses, err = cl.GetPushStatuses(ctx, token, projID, qName, msgID)
+	assert.NoErr(t, err)
+	assert.Equal(t, simulated, statuses, "push statuses")
+
+	_, err = cl.GetPushStatuses(ctx, token, projID, "other", msgID)
+	assert.Err(t, ErrNoSuchMessage, err)
+	assert.Err(t, ErrNoSuchMessage, cl.SetPushStatuses(projID, qName, msgID+"1", simulated))
+
+	// deleting the queue deletes its push records, so a new queue with the same name starts empty
+	_, err = cl.DeleteQueue(ctx, token, projID, qName)
+	assert.NoErr(t, err)
+	_, err = cl.CreateQueue(ctx, token, projID, qName, conf)
+	assert.NoErr(t, err)
+	_, err = cl.GetPushStatuses(ctx, token, projID, qName, msgID)
+	assert.Err(t, ErrNoSuchMessage, err)
+	_, err = cl.GetMessage(ctx, token, projID, qName, msgID)
+	assert.Err(t, ErrNoSuchMessage, err)
+	assert.Equal(t, 0, len(cl.pushed), "number of push records")
+}
+
+func TestMemPushErrorQueue(t *testing.T) {
+	srv := testsrv.StartServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	cl, clock := newClockMemClient()
+	ctx := context.Background()
+	conf := QueueConfig{
+		Type: QueueTypeUnicast,
+		Push: &PushConfig{
+			Subscribers:  []Subscriber{{Name: "a", URL: srv.URLStr()}},
+			Retries:      1,
+			RetriesDelay: 10,
+			ErrorQueue:   "errors",
+		},
+	}
+	_, err := cl.CreateQueue(ctx, token, projID, qName, conf)
+	assert.NoErr(t, err)
+	enq, err := cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", Delay: 5, PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	tries := func() int {
+		statuses, err := cl.GetPushStatuses(ctx, token, projID, qName, enq.IDs[0])
+		assert.NoErr(t, err)
+		return statuses[0].Tries
+	}
+
+	// the push waits for the message's delay, then for the retries delay after it fails
+	assert.Equal(t, 0, tries(), "number of tries before the delay")
+	clock.Advance(5 * time.Second)
+	eventually(t, "the first try", func() bool { return tries() == 1 && numWaiters(clock) == 1 })
+	clock.Advance(9 * time.Second)
+	assert.Equal(t, 1, tries(), "number of tries before the retries delay")
+	clock.Advance(time.Second)
+	var errMsgs []Message
+	eventually(t, "a message on the error queue", func() bool {
+		errMsgs, err = cl.Peek(ctx, token, projID, "errors", 1)
+		return err == nil && len(errMsgs) == 1
+	})
+	errMsg := new(errorQueueMsg)
+	assert.NoErr(t, json.Unmarshal([]byte(errMsgs[0].Body), errMsg))
+	assert.Equal(t, "a", errMsg.SubscriberName, "subscriber name")
+	assert.Equal(t, http.StatusInternalServerError, errMsg.Code, "status code")
+
+	msg, err := cl.GetMessage(ctx, token, projID, qName, errMsg.SourceMsgID)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(msg.PushStatuses), "number of push statuses")
+	assert.Equal(t, 2, msg.PushStatuses[0].Tries, "number of tries")
+	assert.Equal(t, 0, msg.PushStatuses[0].RetriesRemaining, "retries remaining")
+}
+
+func TestMemAlerts(t *testing.T) {
+	cl := NewMemClient()
+	assert.NoErr(t, qAlerts(cl))
+	_, err := cl.AddAlerts(context.Background(), token, projID, "nonexistent", nil)
+	assert.Err(t, ErrNoSuchQueue, err)
+}
+
+// alertMessages dequeues all of the alert messages on qName
+func alertMessages(t *testing.T, cl Client, qName string) []AlertMessage {
+	var ret []AlertMessage
+	for {
+		msgs, err := cl.Peek(context.Background(), token, projID, qName, MaxPerPage)
+		assert.NoErr(t, err)
+		if len(msgs) == 0 {
+			return ret
+		}
+		for _, msg := range msgs {
+			alertMsg := AlertMessage{}
+			assert.NoErr(t, json.Unmarshal([]byte(msg.Body), &alertMsg))
+			ret = append(ret, alertMsg)
+		}
+		_, err = cl.ClearQueue(context.Background(), token, projID, qName)
+		assert.NoErr(t, err)
+	}
+}
+
+func TestMemAlertTriggers(t *testing.T) {
+	cl := NewMemClient()
+	ctx := context.Background()
+	_, err := cl.CreateQueue(ctx, token, projID, qName, QueueConfig{})
+	assert.NoErr(t, err)
+	alerts := []Alert{
+		{Type: AlertTypeFixed, Trigger: 2, Queue: "fixed-up"},
+		{Type: AlertTypeProgressive, Trigger: 2, Direction: AlertDirectionAsc, Queue: "progressive-up"},
+		{Type: AlertTypeFixed, Trigger: 1, Direction: AlertDirectionDesc, Queue: "fixed-down"},
+	}
+	_, err = cl.AddAlerts(ctx, token, projID, qName, alerts)
+	assert.NoErr(t, err)
+
+	for i := 0; i < 5; i++ {
+		_, err := cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+		assert.NoErr(t, err)
+	}
+	fixedUp := alertMessages(t, cl, "fixed-up")
+	assert.Equal(t, 1, len(fixedUp), "number of fixed ascending alerts")
+	assert.Equal(t, 2, fixedUp[0].QueueSize, "queue size in fixed ascending alert")
+	assert.Equal(t, qName, fixedUp[0].SourceQueue, "source queue")
+	progressiveUp := alertMessages(t, cl, "progressive-up")
+	assert.Equal(t, 2, len(progressiveUp), "number of progressive ascending alerts")
+	assert.Equal(t, 4, progressiveUp[1].QueueSize, "queue size in second progressive ascending alert")
+	assert.Equal(t, 0, len(alertMessages(t, cl, "fixed-down")), "number of fixed descending alerts")
+
+	msgs, err := cl.Dequeue(ctx, token, projID, qName, 5, Timeout(30), Wait(1), true)
+	assert.NoErr(t, err)
+	assert.Equal(t, 5, len(msgs), "number of dequeued messages")
+	fixedDown := alertMessages(t, cl, "fixed-down")
+	assert.Equal(t, 1, len(fixedDown), "number of fixed descending alerts")
+	assert.Equal(t, 1, fixedDown[0].QueueSize, "queue size in fixed descending alert")
+	assert.Equal(t, 0, len(alertMessages(t, cl, "fixed-up")), "number of fixed ascending alerts after dequeue")
+}
+
+func TestMemAlertSnooze(t *testing.T) {
+	cl, clock := newClockMemClient()
+	ctx := context.Background()
+	_, err := cl.CreateQueue(ctx, token, projID, qName, QueueConfig{})
+	assert.NoErr(t, err)
+	alerts := []Alert{{Type: AlertTypeProgressive, Trigger: 1, Queue: "alerts", Snooze: 60}}
+	_, err = cl.AddAlerts(ctx, token, projID, qName, alerts)
+	assert.NoErr(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+		assert.NoErr(t, err)
+	}
+	assert.Equal(t, 1, len(alertMessages(t, cl, "alerts")), "number of alerts while snoozed")
+	clock.Advance(60 * time.Second)
+	_, err = cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(alertMessages(t, cl, "alerts")), "number of alerts after the snooze")
+}
+
+func TestMemContextDone(t *testing.T) {
+	cl := NewMemClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	newMsgs := []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}}
+	_, err := cl.Enqueue(ctx, token, projID, qName, newMsgs)
+	assert.Err(t, context.Canceled, err)
+	_, err = cl.CreateQueue(ctx, token, projID, qName, QueueConfig{})
+	assert.Err(t, context.Canceled, err)
+	_, err = cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(1), false)
+	assert.Err(t, context.Canceled, err)
+	_, err = cl.ListQueues(ctx, token, projID, "", "", 0)
+	assert.Err(t, context.Canceled, err)
+	_, err = cl.AddAlerts(ctx, token, projID, qName, nil)
+	assert.Err(t, context.Canceled, err)
+	_, err = cl.GetQueue(context.Background(), token, projID, qName)
+	assert.Err(t, ErrNoSuchQueue, err)
+}
+
+func TestMemDequeueContextDone(t *testing.T) {
+	cl, _ := newClockMemClient()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	msgs, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(MaxWait), false)
+	assert.Err(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 0, len(msgs), "number of dequeued messages")
+	assert.True(t, time.Since(start) < 5*time.Second, "dequeue took %s after its context was done", time.Since(start))
+}
+
+func TestMemDequeueWakesOnEnqueue(t *testing.T) {
+	cl, _ := newClockMemClient()
+	ctx := context.Background()
+	type dequeued struct {
+		msgs []DequeuedMessage
+		err  error
+	}
+	ch := make(chan dequeued)
+	go func() {
+		msgs, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(MaxWait), false)
+		ch <- dequeued{msgs: msgs, err: err}
+	}()
+	eventually(t, "the consumer to wait", func() bool { return cl.Waiting(projID, qName) == 1 })
+	// other operations must not block on the waiting consumer
+	_, err := cl.ListQueues(ctx, token, projID, "", "", 0)
+	assert.NoErr(t, err)
+	_, err = cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	select {
+	case res := <-ch:
+		assert.NoErr(t, res.err)
+		assert.Equal(t, 1, len(res.msgs), "number of dequeued messages")
+		assert.Equal(t, "abc", res.msgs[0].Body, "dequeued message body")
+	case <-time.After(5 * time.Second):
+		t.Fatalf("dequeue didn't return after enqueue")
+	}
+}
+
+func TestMemDequeueConcurrentConsumers(t *testing.T) {
+	const numConsumers = 3
+	cl, _ := newClockMemClient()
+	ctx := context.Background()
+	ch := make(chan []DequeuedMessage, numConsumers)
+	for i := 0; i < numConsumers; i++ {
+		go func() {
+			msgs, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(MaxWait), true)
+			assert.NoErr(t, err)
+			ch <- msgs
+		}()
+	}
+	eventually(t, "the consumers to wait", func() bool { return cl.Waiting(projID, qName) == numConsumers })
+	for i := 0; i < numConsumers; i++ {
+		_, err := cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+		assert.NoErr(t, err)
+	}
+	ids := make(map[string]bool)
+	for i := 0; i < numConsumers; i++ {
+		select {
+		case msgs := <-ch:
+			assert.Equal(t, 1, len(msgs), "number of dequeued messages")
+			ids[msgs[0].ID] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("consumer %d didn't get a message", i)
+		}
+	}
+	assert.Equal(t, numConsumers, len(ids), "number of distinct dequeued messages")
+}
+
+func TestMemDequeueNum(t *testing.T) {
+	cl, _ := newClockMemClient()
+	ctx := context.Background()
+	newMsgs := []NewMessage{
+		{Body: "1", PushHeaders: make(map[string]string)},
+		{Body: "2", PushHeaders: make(map[string]string)},
+		{Body: "3", PushHeaders: make(map[string]string)},
+	}
+	_, err := cl.Enqueue(ctx, token, projID, qName, newMsgs)
+	assert.NoErr(t, err)
+	start := time.Now()
+	msgs, err := cl.Dequeue(ctx, token, projID, qName, 2, Timeout(30), Wait(MaxWait), false)
+	assert.NoErr(t, err)
+	assert.True(t, time.Since(start) < 5*time.Second, "dequeue took %s with enough messages available", time.Since(start))
+	assert.Equal(t, 2, len(msgs), "number of dequeued messages")
+	assert.Equal(t, "1", msgs[0].Body, "first dequeued message body")
+	assert.Equal(t, "2", msgs[1].Body, "second dequeued message body")
+	msgs, err = cl.Dequeue(ctx, token, projID, qName, 2, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(msgs), "number of dequeued messages with no wait")
+}
+
+// newClockMemClient returns a MemClient that uses a FakeClock, and the clock
+func newClockMemClient() (*MemClient, *FakeClock) {
+	clock := NewFakeClock(time.Now())
+	return NewMemClient(WithClock(clock)), clock
+}
+
+func TestMemDeleteReservedIsPermanent(t *testing.T) {
+	cl, clock := newClockMemClient()
+	ctx := context.Background()
+	_, err := cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	msgs, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(msgs), "number of dequeued messages")
+	_, err = cl.DeleteReserved(ctx, token, projID, qName, msgs[0].ID, msgs[0].ReservationID)
+	assert.NoErr(t, err)
+	_, err = cl.DeleteReserved(ctx, token, projID, qName, msgs[0].ID, msgs[0].ReservationID)
+	assert.Err(t, ErrNoSuchReservation, err)
+	// the deleted message's reservation would have expired by now
+	clock.Advance(40 * time.Second)
+	cl.lck.Lock()
+	defer cl.lck.Unlock()
+	assert.Equal(t, 0, len(cl.queues[qKey(projID, qName)]), "queue length")
+	assert.Equal(t, 0, len(cl.reserved), "reserved length")
+	assert.Equal(t, 0, len(cl.releases), "releases length")
+}
+
+func TestMemDeleteExpiredReservation(t *testing.T) {
+	cl, clock := newClockMemClient()
+	ctx := context.Background()
+	_, err := cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	msgs, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(msgs), "number of dequeued messages")
+	// the reservation expires now
+	clock.Advance(31 * time.Second)
+	_, err = cl.DeleteReserved(ctx, token, projID, qName, msgs[0].ID, msgs[0].ReservationID)
+	assert.Err(t, ErrNoSuchReservation, err)
+	peeked, err := cl.Peek(ctx, token, projID, qName, 1)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(peeked), "number of messages on the queue")
+}
+
+func TestMemDeleteReservedWrongQueue(t *testing.T) {
+	cl, _ := newClockMemClient()
+	ctx := context.Background()
+	_, err := cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	msgs, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(msgs), "number of dequeued messages")
+	_, err = cl.DeleteReserved(ctx, token, projID, "other-queue", msgs[0].ID, msgs[0].ReservationID)
+	assert.Err(t, ErrNoSuchReservation, err)
+	_, err = cl.DeleteReserved(ctx, token, "other-proj", qName, msgs[0].ID, msgs[0].ReservationID)
+	assert.Err(t, ErrNoSuchReservation, err)
+	_, err = cl.DeleteReserved(ctx, token, projID, qName, msgs[0].ID+"1", msgs[0].ReservationID)
+	assert.Err(t, ErrNoSuchMessage, err)
+	_, err = cl.DeleteReserved(ctx, token, projID, qName, msgs[0].ID, msgs[0].ReservationID)
+	assert.NoErr(t, err)
+}
+
+func TestMemIDGenerator(t *testing.T) {
+	cl := NewMemClient(WithIDGenerator(NewSequentialIDGenerator(100)))
+	ctx := context.Background()
+	newMsgs := []NewMessage{
+		{Body: "1", PushHeaders: make(map[string]string)},
+		{Body: "2", PushHeaders: make(map[string]string)},
+	}
+	enq, err := cl.Enqueue(ctx, token, projID, qName, newMsgs)
+	assert.NoErr(t, err)
+	assert.Equal(t, []string{"100", "101"}, enq.IDs, "enqueued message IDs")
+	msg, err := cl.GetMessage(ctx, token, projID, qName, "101")
+	assert.NoErr(t, err)
+	assert.Equal(t, "2", msg.Body, "message body")
+
+	cl = NewMemClient(WithIDGenerator(IDGeneratorFunc(func() string { return "fixed" })))
+	enq, err = cl.Enqueue(ctx, token, projID, qName, newMsgs[:1])
+	assert.NoErr(t, err)
+	assert.Equal(t, []string{"fixed"}, enq.IDs, "enqueued message IDs")
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Now()
+	clock := NewFakeClock(start)
+	after := clock.After(2 * time.Second)
+	every := clock.Every(time.Second)
+	var fired []int
+	clock.afterFunc(3*time.Second, func() { fired = append(fired, 3) })
+	clock.afterFunc(time.Second, func() { fired = append(fired, 1) })
+	stop := clock.afterFunc(2*time.Second, func() { fired = append(fired, 2) })
+	stop()
+
+	clock.Advance(time.Second)
+	assert.Equal(t, []int{1}, fired, "funcs fired after 1 second")
+	assert.Equal(t, 1, len(every), "ticks after 1 second")
+	assert.Equal(t, 0, len(after), "after channel length after 1 second")
+	<-every
+	clock.Advance(5 * time.Second)
+	assert.Equal(t, []int{1, 3}, fired, "funcs fired after 6 seconds")
+	assert.Equal(t, start.Add(2*time.Second), <-after, "time received from after channel")
+	assert.Equal(t, 1, len(every), "ticks after 6 seconds")
+	assert.Equal(t, start.Add(6*time.Second), clock.Now(), "clock time")
+}
+
+func TestMemFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cl := NewMemClient(WithClock(clock))
+	ctx := context.Background()
+	_, err := cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", Delay: 10, PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	msgs, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 0, len(msgs), "number of messages dequeued before the delay")
+	clock.Advance(10 * time.Second)
+	msgs, err = cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(msgs), "number of messages dequeued after the delay")
+
+	clock.Advance(29 * time.Second)
+	_, err = cl.Touch(ctx, token, projID, qName, msgs[0].ID, msgs[0].ReservationID, Timeout(60))
+	assert.NoErr(t, err)
+	clock.Advance(59 * time.Second)
+	peeked, err := cl.Peek(ctx, token, projID, qName, 1)
+	assert.NoErr(t, err)
+	assert.Equal(t, 0, len(peeked), "number of messages on the queue before the touched reservation expired")
+	clock.Advance(time.Second)
+	msgs, err = cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(msgs), "number of messages dequeued after the touched reservation expired")
+	assert.Equal(t, 2, msgs[0].ReservedCount, "reserved count")
+}
+
+func TestMemInspection(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cl := NewMemClient(WithClock(clock), WithIDGenerator(NewSequentialIDGenerator(1)))
+	ctx := context.Background()
+	_, err := cl.Enqueue(ctx, token, projID, qName, []NewMessage{
+		{Body: "1", PushHeaders: make(map[string]string)},
+		{Body: "2", PushHeaders: make(map[string]string)},
+		{Body: "3", Delay: 10, PushHeaders: make(map[string]string)},
+	})
+	assert.NoErr(t, err)
+	_, err = cl.Enqueue(ctx, token, projID, "other-queue", []NewMessage{{Body: "4", PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	_, err = cl.Enqueue(ctx, token, "other-proj", qName, []NewMessage{{Body: "5", PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	assert.Equal(t, []string{"other-queue", qName}, cl.Queues(projID), "queue names")
+
+	msgs, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(msgs), "number of dequeued messages")
+	snap := cl.Snapshot(projID, qName)
+	assert.Equal(t, 3, snap.Len(), "number of messages in the snapshot")
+	assert.Equal(t, []Message{{ID: "2", Body: "2", PushHeaders: make(map[string]string)}}, snap.Pending, "pending messages")
+	assert.Equal(t, 1, len(snap.Delayed), "number of delayed messages")
+	assert.Equal(t, "3", snap.Delayed[0].Body, "delayed message body")
+	assert.Equal(t, 1, len(snap.Reserved), "number of reserved messages")
+	assert.Equal(t, msgs[0].ReservationID, snap.Reserved[0].ReservationID, "reserved message reservation ID")
+	found, ok := snap.Find("3")
+	assert.True(t, ok, "delayed message not found in the snapshot")
+	assert.Equal(t, "3", found.ID, "found message ID")
+	assert.Equal(t, 1, cl.Deliveries("1"), "deliveries of the dequeued message")
+	assert.Equal(t, 0, cl.Deliveries("2"), "deliveries of the pending message")
+
+	_, err = cl.DeleteReserved(ctx, token, projID, qName, msgs[0].ID, msgs[0].ReservationID)
+	assert.NoErr(t, err)
+	_, err = cl.DeleteReserved(ctx, token, projID, qName, msgs[0].ID, msgs[0].ReservationID)
+	assert.Err(t, ErrNoSuchReservation, err)
+	assert.Equal(t, []DeleteCall{
+		{ProjectID: projID, QueueName: qName, MessageID: msgs[0].ID, ReservationID: msgs[0].ReservationID},
+		{ProjectID: projID, QueueName: qName, MessageID: msgs[0].ID, ReservationID: msgs[0].ReservationID, Err: ErrNoSuchReservation},
+	}, cl.DeleteCalls(), "delete calls")
+
+	_, err = cl.ClearQueue(ctx, token, projID, qName)
+	assert.NoErr(t, err)
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, 0, cl.Snapshot(projID, qName).Len(), "number of messages after clearing the queue")
+	assert.Equal(t, 0, cl.Snapshot(projID, "no-such-queue").Len(), "number of messages on a queue that doesn't exist")
+}
+
+// numWaiters returns the number of things that are waiting for c to reach a time
+func numWaiters(c *FakeClock) int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return len(c.waiters)
+}
+
+func TestMemFakeClockWaitsStop(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cl := NewMemClient(WithClock(clock))
+	ctx := context.Background()
+	_, err := cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	// deleting dequeues don't reserve, so the only waiter is the dequeue's wait
+	msgs, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(30), true)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(msgs), "number of dequeued messages")
+	assert.Equal(t, 0, numWaiters(clock), "number of waiters after a dequeue that returned before its wait")
+	msgs, err = cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), true)
+	assert.NoErr(t, err)
+	assert.Equal(t, 0, len(msgs), "number of messages dequeued with no wait")
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := cl.Dequeue(cancelCtx, token, projID, qName, 1, Timeout(30), Wait(30), true)
+		errCh <- err
+	}()
+	eventually(t, "the dequeue to wait", func() bool { return numWaiters(clock) == 1 })
+	cancel()
+	assert.Err(t, context.Canceled, <-errCh)
+	assert.Equal(t, 0, numWaiters(clock), "number of waiters after a canceled dequeue")
+
+	cl.InjectFault(Fault{Op: OpGetQueue, Latency: 10 * time.Second})
+	cancelCtx, cancel = context.WithCancel(ctx)
+	go func() {
+		_, err := cl.GetQueue(cancelCtx, token, projID, qName)
+		errCh <- err
+	}()
+	eventually(t, "the call to wait for its latency", func() bool { return numWaiters(clock) == 1 })
+	cancel()
+	assert.Err(t, context.Canceled, <-errCh)
+	assert.Equal(t, 0, numWaiters(clock), "number of waiters after a canceled call with latency")
+}
+
+func TestMemFaultErr(t *testing.T) {
+	cl := NewMemClient()
+	ctx := context.Background()
+	newMsgs := []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}}
+	injected := errors.New("injected error")
+	remove := cl.InjectFault(Fault{Op: OpEnqueue, Queue: qName, Err: injected})
+	_, err := cl.Enqueue(ctx, token, projID, qName, newMsgs)
+	assert.Err(t, injected, err)
+	_, err = cl.Enqueue(ctx, token, projID, "other-queue", newMsgs)
+	assert.NoErr(t, err)
+	_, err = cl.GetQueue(ctx, token, projID, "other-queue")
+	assert.NoErr(t, err)
+	remove()
+	_, err = cl.Enqueue(ctx, token, projID, qName, newMsgs)
+	assert.NoErr(t, err)
+
+	cl.InjectFault(Fault{Op: OpGetQueue, Err: injected, Nth: 2})
+	_, err = cl.GetQueue(ctx, token, projID, qName)
+	assert.NoErr(t, err)
+	_, err = cl.GetQueue(ctx, token, projID, qName)
+	assert.Err(t, injected, err)
+	_, err = cl.GetQueue(ctx, token, projID, qName)
+	assert.NoErr(t, err)
+
+	cl.InjectFault(Fault{ProjectID: "other-proj", Err: injected})
+	_, err = cl.ListQueues(ctx, token, "other-proj", "", "", 0)
+	assert.Err(t, injected, err)
+	_, err = cl.Peek(ctx, token, projID, qName, 1)
+	assert.NoErr(t, err)
+	cl.ClearFaults()
+	_, err = cl.ListQueues(ctx, token, "other-proj", "", "", 0)
+	assert.NoErr(t, err)
+}
+
+func TestMemFaultLatency(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	cl := NewMemClient(WithClock(clock))
+	cl.InjectFault(Fault{Op: OpGetQueue, Latency: 10 * time.Second})
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := cl.GetQueue(ctx, token, projID, qName)
+		errCh <- err
+	}()
+	cancel()
+	assert.Err(t, context.Canceled, <-errCh)
+
+	go func() {
+		_, err := cl.GetQueue(context.Background(), token, projID, qName)
+		errCh <- err
+	}()
+	eventually(t, "the call waits for its latency", func() bool {
+		clock.Advance(10 * time.Second)
+		select {
+		case err := <-errCh:
+			return err == ErrNoSuchQueue
+		default:
+			return false
+		}
+	})
+}
+
+func TestMemFaultDrop(t *testing.T) {
+	cl := NewMemClient()
+	ctx := context.Background()
+	remove := cl.InjectFault(Fault{Op: OpEnqueue, DropRate: 0.5})
+	newMsgs := make([]NewMessage, 4)
+	for i := range newMsgs {
+		newMsgs[i] = NewMessage{Body: strconv.Itoa(i), PushHeaders: make(map[string]string)}
+	}
+	enq, err := cl.Enqueue(ctx, token, projID, qName, newMsgs)
+	assert.NoErr(t, err)
+	assert.Equal(t, 4, len(enq.IDs), "number of enqueued message IDs")
+	remove()
+	peeked, err := cl.Peek(ctx, token, projID, qName, 10)
+	assert.NoErr(t, err)
+	assert.Equal(t, 2, len(peeked), "number of messages on the queue")
+	assert.Equal(t, "0", peeked[0].Body, "first message body")
+	assert.Equal(t, "2", peeked[1].Body, "second message body")
+
+	cl.InjectFault(Fault{Op: OpDequeue, DropRate: 0.5})
+	msgs, err := cl.Dequeue(ctx, token, projID, qName, 2, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(msgs), "number of dequeued messages")
+	assert.Equal(t, "0", msgs[0].Body, "dequeued message body")
+	cl.lck.Lock()
+	defer cl.lck.Unlock()
+	assert.Equal(t, 2, len(cl.reserved), "reserved length")
+}
+
+func TestMemFaultDuplicate(t *testing.T) {
+	cl := NewMemClient()
+	ctx := context.Background()
+	_, err := cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	cl.InjectFault(Fault{Op: OpDequeue, DuplicateRate: 1, Nth: 1})
+	first, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(first), "number of messages in the first dequeue")
+	second, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(second), "number of messages in the second dequeue")
+	assert.Equal(t, first[0].ID, second[0].ID, "duplicated message ID")
+	assert.True(t, first[0].ReservationID != second[0].ReservationID, "duplicate delivered under the same reservation")
+	assert.Equal(t, 2, second[0].ReservedCount, "duplicated message reserved count")
+	assert.Equal(t, 2, cl.Deliveries(first[0].ID), "duplicated message deliveries")
+	third, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 0, len(third), "number of messages in the third dequeue")
+}
+
+func TestMemFaultDuplicateEveryDequeue(t *testing.T) {
+	cl := NewMemClient()
+	ctx := context.Background()
+	_, err := cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	cl.InjectFault(Fault{Op: OpDequeue, DuplicateRate: 1})
+	orig, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(orig), "number of messages in the first dequeue")
+	dup, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(dup), "number of messages in the second dequeue")
+	assert.Equal(t, orig[0].ID, dup[0].ID, "duplicated message ID")
+	// the copy isn't duplicated again, even though the fault applies to every dequeue
+	third, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 0, len(third), "number of messages in the third dequeue")
+
+	// the copies share an ID but are reserved, counted and deleted separately
+	assert.Equal(t, 2, len(cl.Snapshot(projID, qName).Reserved), "number of reserved copies")
+	assert.Equal(t, 2, cl.Deliveries(orig[0].ID), "deliveries of the duplicated message")
+	_, err = cl.DeleteReserved(ctx, token, projID, qName, orig[0].ID, orig[0].ReservationID)
+	assert.NoErr(t, err)
+	snap := cl.Snapshot(projID, qName)
+	assert.Equal(t, 1, snap.Len(), "number of copies left after deleting the original")
+	assert.Equal(t, 1, len(snap.Reserved), "number of reserved copies left after deleting the original")
+	assert.Equal(t, dup[0].ReservationID, snap.Reserved[0].ReservationID, "reservation of the copy that's left")
+	_, err = cl.DeleteReserved(ctx, token, projID, qName, dup[0].ID, dup[0].ReservationID)
+	assert.NoErr(t, err)
+	assert.Equal(t, 0, cl.Snapshot(projID, qName).Len(), "number of copies left after deleting both")
+	assert.Equal(t, 2, cl.Deliveries(orig[0].ID), "deliveries of the deleted message")
+}
+
+func TestMemFaultDuplicateReleasedWithDelay(t *testing.T) {
+	cl, clock := newClockMemClient()
+	ctx := context.Background()
+	_, err := cl.Enqueue(ctx, token, projID, qName, []NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	cl.InjectFault(Fault{Op: OpDequeue, DuplicateRate: 1, Nth: 1})
+	var msgs []DequeuedMessage
+	for i := 0; i < 2; i++ {
+		dequeued, err := cl.Dequeue(ctx, token, projID, qName, 1, Timeout(30), Wait(0), false)
+		assert.NoErr(t, err)
+		assert.Equal(t, 1, len(dequeued), "number of dequeued copies")
+		msgs = append(msgs, dequeued...)
+	}
+	// both copies have the same ID, but each one goes back onto the queue after its delay
+	for _, msg := range msgs {
+		_, err := cl.Release(ctx, token, projID, qName, msg.ID, msg.ReservationID, 10)
+		assert.NoErr(t, err)
+	}
+	assert.Equal(t, 2, len(cl.Snapshot(projID, qName).Delayed), "number of delayed copies")
+	clock.Advance(10 * time.Second)
+	snap := cl.Snapshot(projID, qName)
+	assert.Equal(t, 0, len(snap.Delayed), "number of delayed copies after the delay")
+	assert.Equal(t, 2, len(snap.Pending), "number of pending copies after the delay")
+}
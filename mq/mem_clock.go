@@ -0,0 +1,149 @@
+package mq
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a timer.Timer whose time only moves when Advance is called. Pass it to
+// WithClock so that tests of delayed messages, reservation timeouts and alert snoozes run
+// instantly instead of sleeping for real seconds:
+//
+//  clock := mq.NewFakeClock(time.Now())
+//  cl := mq.NewMemClient(mq.WithClock(clock))
+//  // reserve a message with a 30 second timeout
+//  clock.Advance(30 * time.Second)
+//  // the message is back on the queue
+//
+// A FakeClock is safe for concurrent use
+type FakeClock struct {
+	mtx     sync.Mutex
+	now     time.Time
+	waiters []*clockWaiter
+}
+
+// clockWaiter is something that's waiting for a FakeClock to reach a time
+type clockWaiter struct {
+	at time.Time
+	// the channel to send the time on, for waiters created by After and Every
+	ch chan time.Time
+	// the period to wait again for after firing, for waiters created by Every
+	every time.Duration
+	// the func to call, for waiters created by afterFunc
+	fn func()
+}
+
+// NewFakeClock returns a FakeClock whose current time is now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns c's current time
+func (c *FakeClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+// After is the timer.Timer interface implementation. The returned channel receives once Advance
+// moves c's time at least d past the time of the call, or right away if d isn't positive
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.Now()
+		return ch
+	}
+	c.add(&clockWaiter{at: c.Now().Add(d), ch: ch})
+	return ch
+}
+
+// Every is the timer.Timer interface implementation. Like a time.Ticker, the returned channel
+// drops ticks that its receiver isn't ready for
+func (c *FakeClock) Every(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.add(&clockWaiter{at: c.Now().Add(d), ch: ch, every: d})
+	return ch
+}
+
+// Sleep is the timer.Timer interface implementation. It blocks until Advance moves c's time
+// at least d past the time of the call
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Advance moves c's time forward by d, firing everything that was waiting for a time up to
+// the new one in the order of the times they were waiting for. Work that a MemClient
+// scheduled on c (putting delayed messages onto their queues, expiring reservations and
+// ending alert snoozes) is done before Advance returns. Goroutines that were waiting on
+// channels from After, Every or Sleep are woken but may not have run yet. Advance must not be
+// called concurrently with itself
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mtx.Lock()
+	target := c.now.Add(d)
+	c.mtx.Unlock()
+	for {
+		w := c.next(target)
+		if w == nil {
+			break
+		}
+		if w.fn != nil {
+			w.fn()
+			continue
+		}
+		select {
+		case w.ch <- w.at:
+		default:
+		}
+	}
+	c.mtx.Lock()
+	c.now = target
+	c.mtx.Unlock()
+}
+
+// add starts w waiting on c
+func (c *FakeClock) add(w *clockWaiter) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.waiters = append(c.waiters, w)
+}
+
+// next removes and returns the waiter with the earliest time that's not after target, and
+// moves c's time to that waiter's time. Waiters created by Every start waiting again.
+// Returns nil if no waiter's time is at or before target
+func (c *FakeClock) next(target time.Time) *clockWaiter {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	idx := -1
+	for i, w := range c.waiters {
+		if !w.at.After(target) && (idx < 0 || w.at.Before(c.waiters[idx].at)) {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return nil
+	}
+	w := c.waiters[idx]
+	c.waiters = append(c.waiters[:idx], c.waiters[idx+1:]...)
+	c.now = w.at
+	if w.every > 0 {
+		c.waiters = append(c.waiters, &clockWaiter{at: w.at.Add(w.every), ch: w.ch, every: w.every})
+	}
+	return w
+}
+
+// afterFunc calls fn from the Advance call that moves c's time at least d past the time of
+// the call. Returns a func that stops fn from being called if it hasn't been already
+func (c *FakeClock) afterFunc(d time.Duration, fn func()) (stop func()) {
+	w := &clockWaiter{at: c.Now().Add(d), fn: fn}
+	c.add(w)
+	return func() {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		for i, waiter := range c.waiters {
+			if waiter == w {
+				c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+				return
+			}
+		}
+	}
+}
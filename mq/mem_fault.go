@@ -0,0 +1,185 @@
+package mq
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Op names a Client operation. Faults use it to choose the operations that they apply to
+type Op string
+
+// The Client operations that faults can apply to
+const (
+	// OpAll matches every operation
+	OpAll                 Op = ""
+	OpEnqueue             Op = "Enqueue"
+	OpPostWebhook         Op = "PostWebhook"
+	OpDequeue             Op = "Dequeue"
+	OpDeleteReserved      Op = "DeleteReserved"
+	OpDeleteReservedBatch Op = "DeleteReservedBatch"
+	OpCreateQueue         Op = "CreateQueue"
+	OpGetQueue            Op = "GetQueue"
+	OpUpdateQueue         Op = "UpdateQueue"
+	OpDeleteQueue         Op = "DeleteQueue"
+	OpClearQueue          Op = "ClearQueue"
+	OpAddSubscribers      Op = "AddSubscribers"
+	OpReplaceSubscribers  Op = "ReplaceSubscribers"
+	OpRemoveSubscribers   Op = "RemoveSubscribers"
+	OpAddAlerts           Op = "AddAlerts"
+	OpReplaceAlerts       Op = "ReplaceAlerts"
+	OpDeleteAlert         Op = "DeleteAlert"
+	OpListQueues          Op = "ListQueues"
+	OpPeek                Op = "Peek"
+	OpGetMessage          Op = "GetMessage"
+	OpGetPushStatuses     Op = "GetPushStatuses"
+	OpTouch               Op = "Touch"
+	OpRelease             Op = "Release"
+)
+
+// Fault is a failure that a MemClient injects into calls of its Client funcs, so tests can
+// check how code behaves when IronMQ errors, is slow or returns partial results. Pass it to
+// MemClient.InjectFault
+type Fault struct {
+	// Op is the operation that the fault applies to. OpAll matches every operation
+	Op Op
+	// ProjectID is the ID of the project that the fault applies to. Empty matches every project
+	ProjectID string
+	// Queue is the name of the queue that the fault applies to. Empty matches every queue.
+	// ListQueues calls only match faults with an empty Queue
+	Queue string
+	// Err is returned from matching calls instead of doing the operation, if it's non-nil
+	Err error
+	// Latency is how long matching calls wait on the client's clock before doing the operation.
+	// Calls return ctx.Err() if ctx.Done() receives while they wait
+	Latency time.Duration
+	// DropRate is the fraction, from 0 to 1, of messages that matching calls lose. Enqueue and
+	// PostWebhook return IDs for dropped messages but never put them onto the queue. Dequeue
+	// leaves dropped messages out of its result, but still reserves them, so they go back onto
+	// the queue when their reservations expire unless the dequeue deleted them. Drops are
+	// spread evenly, so a DropRate of 0.5 drops every second message
+	DropRate float64
+	// DuplicateRate is the fraction, from 0 to 1, of messages that matching Dequeue calls
+	// deliver twice. A duplicated message is returned as usual and a copy of it goes back
+	// onto the front of the queue, so the next dequeue delivers it again under a new
+	// reservation. The copy has the same ID, body and reserved count as the message, but it's
+	// a separate message on the queue: deleting or releasing one reservation leaves the other
+	// copy where it is, MemClient.Snapshot lists each copy, and MemClient.Deliveries counts the
+	// deliveries of both. Copies are never duplicated again, so each message is delivered at
+	// most one extra time per dequeue that duplicates it. Duplicates are spread evenly, like
+	// drops
+	DuplicateRate float64
+	// Nth makes the fault apply only to the Nth matching call after it was injected, counting
+	// from 1, if it's positive. Otherwise the fault applies to every matching call
+	Nth int
+}
+
+// memFault is a Fault that's injected into a MemClient
+type memFault struct {
+	Fault
+	// the number of matching calls so far
+	calls int
+	// the fractions of a message that are owed to drops and duplicates
+	dropDebt, dupDebt float64
+}
+
+// matches returns whether f applies to calls of op on qName in the project with ID projID
+func (f *memFault) matches(op Op, projID, qName string) bool {
+	return (f.Op == OpAll || f.Op == op) &&
+		(f.ProjectID == "" || f.ProjectID == projID) &&
+		(f.Queue == "" || f.Queue == qName)
+}
+
+// callFaults is the faults that apply to a single call
+type callFaults []*memFault
+
+// drop returns whether to drop the next message of the call. Must be called with m.lck held
+func (c callFaults) drop() bool {
+	ret := false
+	for _, f := range c {
+		if f.dropDebt += f.DropRate; f.dropDebt >= 1 {
+			f.dropDebt--
+			ret = true
+		}
+	}
+	return ret
+}
+
+// duplicate returns whether to duplicate the next message of the call. Must be called with
+// m.lck held
+func (c callFaults) duplicate() bool {
+	ret := false
+	for _, f := range c {
+		if f.dupDebt += f.DuplicateRate; f.dupDebt >= 1 {
+			f.dupDebt--
+			ret = true
+		}
+	}
+	return ret
+}
+
+// InjectFault makes m inject f into every matching call of its Client funcs from now on, in
+// addition to the faults that are already injected. Faults can be injected and removed while
+// other goroutines use m. Returns a func that removes the fault
+func (m *MemClient) InjectFault(f Fault) (remove func()) {
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	fault := &memFault{Fault: f}
+	m.faults = append(m.faults, fault)
+	return func() {
+		m.lck.Lock()
+		defer m.lck.Unlock()
+		for i, existing := range m.faults {
+			if existing == fault {
+				m.faults = append(m.faults[:i], m.faults[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// ClearFaults removes every fault that was injected into m
+func (m *MemClient) ClearFaults() {
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	m.faults = nil
+}
+
+// injectFaults counts a call of op on qName against the faults that match it, waits for
+// their latency and returns the first of their errors. Returns the faults that apply to the
+// call and a nil error if the call should go ahead
+func (m *MemClient) injectFaults(ctx context.Context, op Op, projID, qName string) (callFaults, error) {
+	m.lck.Lock()
+	var ret callFaults
+	var latency time.Duration
+	var err error
+	for _, f := range m.faults {
+		if !f.matches(op, projID, qName) {
+			continue
+		}
+		f.calls++
+		if f.Nth > 0 && f.calls != f.Nth {
+			continue
+		}
+		ret = append(ret, f)
+		latency += f.Latency
+		if err == nil {
+			err = f.Err
+		}
+	}
+	m.lck.Unlock()
+	if latency > 0 {
+		latencyCh := make(chan struct{})
+		stop := m.afterFunc(latency, func() { close(latencyCh) })
+		defer stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-latencyCh:
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
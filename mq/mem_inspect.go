@@ -0,0 +1,130 @@
+package mq
+
+import (
+	"sort"
+	"strings"
+)
+
+// QueueSnapshot is a copy of the messages on a MemClient queue at a single point in time.
+// MemClient.Snapshot returns it
+type QueueSnapshot struct {
+	// Pending is the messages that are waiting to be dequeued, in the order they'll be dequeued
+	Pending []Message
+	// Delayed is the messages whose delays haven't passed yet, in the order they were created
+	Delayed []Message
+	// Reserved is the messages that are reserved, in the order they were created
+	Reserved []Message
+}
+
+// Len returns the number of messages in s
+func (s QueueSnapshot) Len() int {
+	return len(s.Pending) + len(s.Delayed) + len(s.Reserved)
+}
+
+// Find returns the first message in s with the given body, looking in s.Pending, then
+// s.Delayed, then s.Reserved. Returns false if there's no such message
+func (s QueueSnapshot) Find(body string) (Message, bool) {
+	for _, msgs := range [][]Message{s.Pending, s.Delayed, s.Reserved} {
+		for _, msg := range msgs {
+			if msg.Body == body {
+				return msg, true
+			}
+		}
+	}
+	return Message{}, false
+}
+
+// DeleteCall is the record of a single message delete that a MemClient received through
+// DeleteReserved or DeleteReservedBatch. MemClient.DeleteCalls returns them
+type DeleteCall struct {
+	ProjectID     string
+	QueueName     string
+	MessageID     string
+	ReservationID string
+	// Err is the error that the delete returned for the message, or nil if it succeeded
+	Err error
+}
+
+// Queues returns the sorted names of all of the queues in the project with ID projID. Unlike
+// ListQueues, it doesn't paginate or honor a context. It's intended for assertions in tests
+func (m *MemClient) Queues(projID string) []string {
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	keyPrefix := qKey(projID, "")
+	var ret []string
+	for key := range m.queues {
+		if strings.HasPrefix(key, keyPrefix) {
+			ret = append(ret, strings.TrimPrefix(key, keyPrefix))
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Snapshot returns a copy of the messages on qName without dequeuing, reserving or
+// otherwise changing them. Returns an empty snapshot if the queue doesn't exist. It's
+// intended for assertions in tests
+func (m *MemClient) Snapshot(projID, qName string) QueueSnapshot {
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	key := qKey(projID, qName)
+	var ret QueueSnapshot
+	for _, msg := range m.queues[key] {
+		ret.Pending = append(ret.Pending, msg.message())
+	}
+	var delayed, reserved []memMsg
+	for _, msg := range m.delayed {
+		delayed = append(delayed, msg)
+	}
+	for _, msg := range m.reserved {
+		reserved = append(reserved, msg)
+	}
+	ret.Delayed = sortedMessages(delayed, key)
+	ret.Reserved = sortedMessages(reserved, key)
+	return ret
+}
+
+// sortedMessages returns the messages in msgs that are on the queue at key, in the order
+// they were created
+func sortedMessages(msgs []memMsg, key string) []Message {
+	var onQueue []memMsg
+	for _, msg := range msgs {
+		if msg.queue == key {
+			onQueue = append(onQueue, msg)
+		}
+	}
+	sort.Slice(onQueue, func(i, j int) bool {
+		return onQueue[i].seq < onQueue[j].seq
+	})
+	var ret []Message
+	for _, msg := range onQueue {
+		ret = append(ret, msg.message())
+	}
+	return ret
+}
+
+// Deliveries returns the number of times that the message with ID messageID was dequeued,
+// including dequeues that deleted it. Returns 0 for messages that were never dequeued,
+// including messages that were pushed to subscribers
+func (m *MemClient) Deliveries(messageID string) int {
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	return m.deliveries[messageID]
+}
+
+// Waiting returns the number of Dequeue calls that are waiting for messages to go onto
+// qName. Tests can wait for it to reach the number of consumers they started, instead of
+// sleeping, before they enqueue the messages that the consumers should get
+func (m *MemClient) Waiting(projID, qName string) int {
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	return m.waiting[qKey(projID, qName)]
+}
+
+// DeleteCalls returns a record of every delete of a reserved message that m received, in
+// the order it received them. Failed deletes are included, with their errors
+func (m *MemClient) DeleteCalls() []DeleteCall {
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	return append([]DeleteCall(nil), m.deletes...)
+}
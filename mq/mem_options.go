@@ -0,0 +1,65 @@
+package mq
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pivotal-golang/timer"
+)
+
+// IDGenerator generates the IDs of the messages that a MemClient enqueues. Implementations
+// must be safe for concurrent use and must never return the same ID twice
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc is an IDGenerator that calls itself to generate each ID
+type IDGeneratorFunc func() string
+
+// NewID is the IDGenerator interface implementation
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+type sequentialIDGenerator struct {
+	// the next ID, minus 1
+	last uint64
+}
+
+// NewSequentialIDGenerator returns an IDGenerator that generates the decimal forms of
+// start, start+1, start+2 and so on. Pass it to WithIDGenerator to make message IDs
+// deterministic in tests
+func NewSequentialIDGenerator(start uint64) IDGenerator {
+	return &sequentialIDGenerator{last: start - 1}
+}
+
+// NewID is the IDGenerator interface implementation
+func (s *sequentialIDGenerator) NewID() string {
+	return strconv.FormatUint(atomic.AddUint64(&s.last, 1), 10)
+}
+
+// newDefaultIDGenerator returns the IDGenerator that NewMemClient uses when none is passed.
+// Like IronMQ, it generates large numeric strings
+func newDefaultIDGenerator() IDGenerator {
+	return NewSequentialIDGenerator(uint64(time.Now().UnixNano()))
+}
+
+// MemOption configures a MemClient. Pass MemOptions to NewMemClient
+type MemOption func(*MemClient)
+
+// WithIDGenerator makes the MemClient generate message IDs with gen
+func WithIDGenerator(gen IDGenerator) MemOption {
+	return func(m *MemClient) {
+		m.ids = gen
+	}
+}
+
+// WithClock makes the MemClient measure message delays, reservation timeouts, long polls,
+// push retry delays and alert snoozes with tmr instead of the real clock. Pass a FakeClock
+// to control time deterministically in tests
+func WithClock(tmr timer.Timer) MemOption {
+	return func(m *MemClient) {
+		m.tmr = tmr
+	}
+}
@@ -0,0 +1,281 @@
+package mq
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// pushTimeout is the time limit for a single push to a subscriber
+const pushTimeout = 60 * time.Second
+
+// memPush is the delivery record of a message on a push queue
+type memPush struct {
+	msg memMsg
+	// the status of each subscriber, in the same order as the queue's subscribers at the time the message was enqueued
+	statuses []PushStatus
+}
+
+// errorQueueMsg is the body of the message that goes onto a push queue's error queue when
+// all pushes of a message to a subscriber fail
+type errorQueueMsg struct {
+	SourceMsgID    string `json:"source_msg_id"`
+	SubscriberName string `json:"subscriber_name"`
+	Code           int    `json:"code"`
+	Msg            string `json:"msg"`
+}
+
+// startPush records msg as pushed and schedules its delivery to the subscribers in conf.
+// Must be called with m.lck held
+func (m *MemClient) startPush(projID string, msg memMsg, conf QueueConfig) {
+	push := conf.Push.clone()
+	statuses := make([]PushStatus, len(push.Subscribers))
+	for i, sub := range push.Subscribers {
+		statuses[i] = PushStatus{SubscriberName: sub.Name, URL: sub.URL, RetriesRemaining: push.Retries, Msg: "pending"}
+	}
+	m.pushed[msg.ID] = &memPush{msg: msg, statuses: statuses}
+	m.pushAfter(time.Duration(int(msg.Delay))*time.Second, func() {
+		m.deliver(projID, msg, conf.Type, *push)
+	})
+}
+
+// pushAfter calls fn in its own goroutine once d elapses on m's clock, or right away if d
+// isn't positive. Like afterFunc, the wait starts when pushAfter is called
+func (m *MemClient) pushAfter(d time.Duration, fn func()) {
+	if d <= 0 {
+		go fn()
+		return
+	}
+	m.afterFunc(d, func() { go fn() })
+}
+
+// deliver pushes msg to the subscribers in conf. Multicast queues push to every subscriber
+// in parallel, and unicast queues push to one subscriber at a time until a push succeeds
+func (m *MemClient) deliver(projID string, msg memMsg, typ string, conf PushConfig) {
+	if typ == QueueTypeUnicast {
+		m.deliverTo(projID, msg, conf.Subscribers, conf, 0)
+		return
+	}
+	for _, sub := range conf.Subscribers {
+		go m.deliverTo(projID, msg, []Subscriber{sub}, conf, 0)
+	}
+}
+
+// deliverTo makes try number try, counting from 0, to push msg to subs. Each try pushes to
+// the next subscriber in subs. If the push fails, deliverTo schedules the next try after
+// conf.RetriesDelay on m's clock, or enqueues a message onto conf.ErrorQueue if conf.Retries
+// retries have failed. Does nothing if subs is empty
+func (m *MemClient) deliverTo(projID string, msg memMsg, subs []Subscriber, conf PushConfig, try int) {
+	if len(subs) == 0 {
+		return
+	}
+	sub := subs[try%len(subs)]
+	code, err := m.pushOnce(msg, sub)
+	m.recordPush(msg.ID, sub.Name, code, err, conf.Retries-try)
+	if err == nil {
+		return
+	}
+	if try < conf.Retries {
+		m.pushAfter(time.Duration(conf.RetriesDelay)*time.Second, func() {
+			m.deliverTo(projID, msg, subs, conf, try+1)
+		})
+		return
+	}
+	if conf.ErrorQueue == "" {
+		return
+	}
+	body, jsonErr := json.Marshal(errorQueueMsg{SourceMsgID: msg.ID, SubscriberName: sub.Name, Code: code, Msg: err.Error()})
+	if jsonErr != nil {
+		return
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	m.enqueue(projID, conf.ErrorQueue, NewMessage{Body: string(body), PushHeaders: make(map[string]string)})
+	m.checkAlerts(projID, conf.ErrorQueue)
+}
+
+// pushOnce POSTs msg to sub. Returns the response status code, and a non-nil error if the
+// push failed or the status code wasn't 2xx
+func (m *MemClient) pushOnce(msg memMsg, sub Subscriber) (int, error) {
+	req, err := http.NewRequest("POST", sub.URL, strings.NewReader(msg.Body))
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range sub.Headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range msg.PushHeaders {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Iron-Message-Id", msg.ID)
+	req.Header.Set("Iron-Subscriber-Name", sub.Name)
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+	resp, err := m.pushClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// recordPush records the result of a single push of the message with ID msgID to the
+// subscriber called subName
+func (m *MemClient) recordPush(msgID string, subName string, code int, err error, retriesRemaining int) {
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	p, ok := m.pushed[msgID]
+	if !ok {
+		return
+	}
+	for i := range p.statuses {
+		status := &p.statuses[i]
+		if status.SubscriberName != subName {
+			continue
+		}
+		status.Tries++
+		status.StatusCode = code
+		status.RetriesRemaining = retriesRemaining
+		if err == nil {
+			status.Msg = "delivered"
+		} else if retriesRemaining > 0 {
+			status.Msg = "retrying (" + err.Error() + ")"
+		} else {
+			status.Msg = "failed (" + err.Error() + ")"
+		}
+	}
+}
+
+// GetPushStatuses is the interface implementation
+func (m *MemClient) GetPushStatuses(ctx context.Context, token, projID, qName string, messageID string) ([]PushStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpGetPushStatuses, projID, qName); err != nil {
+		return nil, err
+	}
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	p, ok := m.pushed[messageID]
+	if !ok || p.msg.queue != qKey(projID, qName) {
+		return nil, ErrNoSuchMessage
+	}
+	return append([]PushStatus(nil), p.statuses...), nil
+}
+
+// SetPushStatuses replaces the push statuses of the message with the given message ID
+// that was pushed from qName, so tests can simulate delivery outcomes without running
+// subscribers. Deliveries of the message that are still in progress keep updating the
+// statuses whose subscriber names match. Returns ErrNoSuchMessage if the message was
+// never pushed from qName
+func (m *MemClient) SetPushStatuses(projID, qName string, messageID string, statuses []PushStatus) error {
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	p, ok := m.pushed[messageID]
+	if !ok || p.msg.queue != qKey(projID, qName) {
+		return ErrNoSuchMessage
+	}
+	p.statuses = append([]PushStatus(nil), statuses...)
+	return nil
+}
+
+// AddSubscribers is the interface implementation
+func (m *MemClient) AddSubscribers(ctx context.Context, token, projID, qName string, subs []Subscriber) (*Updated, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpAddSubscribers, projID, qName); err != nil {
+		return nil, err
+	}
+	for _, sub := range subs {
+		if err := sub.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return m.updateSubscribers(projID, qName, func(existing []Subscriber) []Subscriber {
+		names := make(map[string]bool)
+		for _, sub := range subs {
+			names[sub.Name] = true
+		}
+		var ret []Subscriber
+		for _, sub := range existing {
+			if !names[sub.Name] {
+				ret = append(ret, sub)
+			}
+		}
+		return append(ret, subs...)
+	})
+}
+
+// ReplaceSubscribers is the interface implementation
+func (m *MemClient) ReplaceSubscribers(ctx context.Context, token, projID, qName string, subs []Subscriber) (*Updated, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpReplaceSubscribers, projID, qName); err != nil {
+		return nil, err
+	}
+	if len(subs) == 0 {
+		return nil, ErrNoSubscribers
+	}
+	for _, sub := range subs {
+		if err := sub.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return m.updateSubscribers(projID, qName, func([]Subscriber) []Subscriber {
+		return append([]Subscriber(nil), subs...)
+	})
+}
+
+// RemoveSubscribers is the interface implementation
+func (m *MemClient) RemoveSubscribers(ctx context.Context, token, projID, qName string, names []string) (*Updated, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := m.injectFaults(ctx, OpRemoveSubscribers, projID, qName); err != nil {
+		return nil, err
+	}
+	return m.updateSubscribers(projID, qName, func(existing []Subscriber) []Subscriber {
+		remove := make(map[string]bool)
+		for _, name := range names {
+			remove[name] = true
+		}
+		var ret []Subscriber
+		for _, sub := range existing {
+			if !remove[sub.Name] {
+				ret = append(ret, sub)
+			}
+		}
+		return ret
+	})
+}
+
+// updateSubscribers sets the subscribers of the push queue qName to the result of
+// calling update on its existing subscribers. Returns ErrNoSubscribers and leaves the
+// subscribers as they were if the result is empty
+func (m *MemClient) updateSubscribers(projID, qName string, update func([]Subscriber) []Subscriber) (*Updated, error) {
+	m.lck.Lock()
+	defer m.lck.Unlock()
+	key := qKey(projID, qName)
+	if _, ok := m.queues[key]; !ok {
+		return nil, ErrNoSuchQueue
+	}
+	conf := &m.queueMeta(key).conf
+	if !isPushType(conf.Type) || conf.Push == nil {
+		return nil, ErrNotPushQueue
+	}
+	subs := update(conf.Push.Subscribers)
+	if len(subs) == 0 {
+		return nil, ErrNoSubscribers
+	}
+	conf.Push.Subscribers = subs
+	return &Updated{Msg: "Updated"}, nil
+}
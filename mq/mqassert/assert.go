@@ -0,0 +1,155 @@
+// Package mqassert provides test assertions about the queues and messages in an mq.MemClient.
+// Each assertion inspects the client without dequeuing, reserving or otherwise changing its
+// messages, and fails the test immediately if it doesn't hold:
+//
+//  cl := mq.NewMemClient()
+//  myService := NewMyService(cl)
+//  myService.DoThing()
+//  mqassert.Enqueued(t, cl, "my-proj", "my-queue", "thing done")
+//
+// Assertions take the project ID that the code under test passes to the client. Use the
+// mqtest package's Server.Mem to make assertions about a client that's behind a test server
+package mqassert
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/arschles/gorion/mq"
+)
+
+// QueueExists asserts that the queue called qName exists
+func QueueExists(t testing.TB, cl *mq.MemClient, projID, qName string) {
+	t.Helper()
+	queues := cl.Queues(projID)
+	for _, name := range queues {
+		if name == qName {
+			return
+		}
+	}
+	t.Fatalf("queue %s not found in project %s (queues: %v)", qName, projID, queues)
+}
+
+// NoQueue asserts that the queue called qName doesn't exist
+func NoQueue(t testing.TB, cl *mq.MemClient, projID, qName string) {
+	t.Helper()
+	for _, name := range cl.Queues(projID) {
+		if name == qName {
+			t.Fatalf("queue %s exists in project %s", qName, projID)
+		}
+	}
+}
+
+// Enqueued asserts that a message with the given body is on qName, whether it's pending,
+// delayed or reserved. Returns the first such message
+func Enqueued(t testing.TB, cl *mq.MemClient, projID, qName, body string) mq.Message {
+	t.Helper()
+	snap := cl.Snapshot(projID, qName)
+	msg, ok := snap.Find(body)
+	if !ok {
+		t.Fatalf("no message with body %q on queue %s (%s)", body, qName, describe(snap))
+	}
+	return msg
+}
+
+// NotEnqueued asserts that no message with the given body is on qName
+func NotEnqueued(t testing.TB, cl *mq.MemClient, projID, qName, body string) {
+	t.Helper()
+	snap := cl.Snapshot(projID, qName)
+	if msg, ok := snap.Find(body); ok {
+		t.Fatalf("message %s with body %q is on queue %s", msg.ID, body, qName)
+	}
+}
+
+// Pending asserts that the bodies of the messages that are waiting to be dequeued from
+// qName are exactly bodies, in order
+func Pending(t testing.TB, cl *mq.MemClient, projID, qName string, bodies ...string) {
+	t.Helper()
+	snap := cl.Snapshot(projID, qName)
+	actual := messageBodies(snap.Pending)
+	if len(actual) != len(bodies) {
+		t.Fatalf("expected pending bodies %q on queue %s, got %q", bodies, qName, actual)
+	}
+	for i, body := range bodies {
+		if actual[i] != body {
+			t.Fatalf("expected pending bodies %q on queue %s, got %q", bodies, qName, actual)
+		}
+	}
+}
+
+// QueueLen asserts that qName has n messages that are pending, delayed or reserved
+func QueueLen(t testing.TB, cl *mq.MemClient, projID, qName string, n int) {
+	t.Helper()
+	snap := cl.Snapshot(projID, qName)
+	if snap.Len() != n {
+		t.Fatalf("expected %d messages on queue %s, got %d (%s)", n, qName, snap.Len(), describe(snap))
+	}
+}
+
+// NumDelayed asserts that qName has n messages whose delays haven't passed yet
+func NumDelayed(t testing.TB, cl *mq.MemClient, projID, qName string, n int) {
+	t.Helper()
+	if delayed := cl.Snapshot(projID, qName).Delayed; len(delayed) != n {
+		t.Fatalf("expected %d delayed messages on queue %s, got %d", n, qName, len(delayed))
+	}
+}
+
+// NumReserved asserts that qName has n reserved messages
+func NumReserved(t testing.TB, cl *mq.MemClient, projID, qName string, n int) {
+	t.Helper()
+	if reserved := cl.Snapshot(projID, qName).Reserved; len(reserved) != n {
+		t.Fatalf("expected %d reserved messages on queue %s, got %d", n, qName, len(reserved))
+	}
+}
+
+// Delivered asserts that the message with ID messageID was dequeued exactly n times
+func Delivered(t testing.TB, cl *mq.MemClient, messageID string, n int) {
+	t.Helper()
+	if actual := cl.Deliveries(messageID); actual != n {
+		t.Fatalf("expected message %s to be delivered %d times, got %d", messageID, n, actual)
+	}
+}
+
+// Deleted asserts that the message with ID messageID was successfully deleted from qName
+// with DeleteReserved or DeleteReservedBatch
+func Deleted(t testing.TB, cl *mq.MemClient, projID, qName, messageID string) {
+	t.Helper()
+	if !deleted(cl, projID, qName, messageID) {
+		t.Fatalf("message %s wasn't deleted from queue %s", messageID, qName)
+	}
+}
+
+// NotDeleted asserts that the message with ID messageID wasn't successfully deleted from
+// qName with DeleteReserved or DeleteReservedBatch
+func NotDeleted(t testing.TB, cl *mq.MemClient, projID, qName, messageID string) {
+	t.Helper()
+	if deleted(cl, projID, qName, messageID) {
+		t.Fatalf("message %s was deleted from queue %s", messageID, qName)
+	}
+}
+
+// deleted returns whether cl recorded a successful delete of the message with ID messageID
+// from qName
+func deleted(cl *mq.MemClient, projID, qName, messageID string) bool {
+	for _, call := range cl.DeleteCalls() {
+		if call.ProjectID == projID && call.QueueName == qName && call.MessageID == messageID && call.Err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// messageBodies returns the bodies of msgs
+func messageBodies(msgs []mq.Message) []string {
+	ret := make([]string, len(msgs))
+	for i, msg := range msgs {
+		ret[i] = msg.Body
+	}
+	return ret
+}
+
+// describe returns a description of the messages in snap for failure messages
+func describe(snap mq.QueueSnapshot) string {
+	return fmt.Sprintf("pending: %q, delayed: %q, reserved: %q",
+		messageBodies(snap.Pending), messageBodies(snap.Delayed), messageBodies(snap.Reserved))
+}
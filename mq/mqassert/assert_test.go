@@ -0,0 +1,98 @@
+package mqassert
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/arschles/assert"
+	"github.com/arschles/gorion/mq"
+	"golang.org/x/net/context"
+)
+
+const (
+	projID = "test-proj"
+	qName  = "test-queue"
+)
+
+// recorder is a testing.TB that records whether a test failed
+type recorder struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.failed = true
+	r.msg = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// fails returns whether fn fails the testing.TB that's passed to it, and the failure message
+func fails(fn func(testing.TB)) (bool, string) {
+	r := &recorder{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(r)
+	}()
+	<-done
+	return r.failed, r.msg
+}
+
+func TestAssertions(t *testing.T) {
+	cl := mq.NewMemClient()
+	ctx := context.Background()
+	enq, err := cl.Enqueue(ctx, "", projID, qName, []mq.NewMessage{
+		{Body: "1", PushHeaders: make(map[string]string)},
+		{Body: "2", PushHeaders: make(map[string]string)},
+		{Body: "3", Delay: 60, PushHeaders: make(map[string]string)},
+	})
+	assert.NoErr(t, err)
+	msgs, err := cl.Dequeue(ctx, "", projID, qName, 1, mq.Timeout(mq.MinTimeout), 0, false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(msgs), "number of dequeued messages")
+
+	passing := map[string]func(testing.TB){
+		"QueueExists": func(t testing.TB) { QueueExists(t, cl, projID, qName) },
+		"NoQueue":     func(t testing.TB) { NoQueue(t, cl, projID, "other-queue") },
+		"Enqueued":    func(t testing.TB) { Enqueued(t, cl, projID, qName, "3") },
+		"NotEnqueued": func(t testing.TB) { NotEnqueued(t, cl, projID, qName, "4") },
+		"Pending":     func(t testing.TB) { Pending(t, cl, projID, qName, "2") },
+		"QueueLen":    func(t testing.TB) { QueueLen(t, cl, projID, qName, 3) },
+		"NumDelayed":  func(t testing.TB) { NumDelayed(t, cl, projID, qName, 1) },
+		"NumReserved": func(t testing.TB) { NumReserved(t, cl, projID, qName, 1) },
+		"Delivered":   func(t testing.TB) { Delivered(t, cl, enq.IDs[0], 1) },
+		"NotDeleted":  func(t testing.TB) { NotDeleted(t, cl, projID, qName, enq.IDs[0]) },
+	}
+	for name, fn := range passing {
+		failed, msg := fails(fn)
+		assert.False(t, failed, "%s failed (%s)", name, msg)
+	}
+
+	failing := map[string]func(testing.TB){
+		"QueueExists": func(t testing.TB) { QueueExists(t, cl, projID, "other-queue") },
+		"NoQueue":     func(t testing.TB) { NoQueue(t, cl, projID, qName) },
+		"Enqueued":    func(t testing.TB) { Enqueued(t, cl, projID, qName, "4") },
+		"NotEnqueued": func(t testing.TB) { NotEnqueued(t, cl, projID, qName, "1") },
+		"Pending":     func(t testing.TB) { Pending(t, cl, projID, qName, "1", "2") },
+		"QueueLen":    func(t testing.TB) { QueueLen(t, cl, projID, qName, 2) },
+		"NumDelayed":  func(t testing.TB) { NumDelayed(t, cl, projID, qName, 0) },
+		"NumReserved": func(t testing.TB) { NumReserved(t, cl, projID, qName, 0) },
+		"Delivered":   func(t testing.TB) { Delivered(t, cl, enq.IDs[1], 1) },
+		"Deleted":     func(t testing.TB) { Deleted(t, cl, projID, qName, enq.IDs[0]) },
+	}
+	for name, fn := range failing {
+		failed, _ := fails(fn)
+		assert.True(t, failed, "%s didn't fail", name)
+	}
+
+	_, err = cl.DeleteReserved(ctx, "", projID, qName, msgs[0].ID, msgs[0].ReservationID)
+	assert.NoErr(t, err)
+	failed, msg := fails(func(t testing.TB) { Deleted(t, cl, projID, qName, msgs[0].ID) })
+	assert.False(t, failed, "Deleted failed after the delete (%s)", msg)
+	failed, _ = fails(func(t testing.TB) { NotDeleted(t, cl, projID, qName, msgs[0].ID) })
+	assert.True(t, failed, "NotDeleted didn't fail after the delete")
+}
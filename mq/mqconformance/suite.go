@@ -0,0 +1,380 @@
+// Package mqconformance provides a test suite that checks that an mq.Client implementation
+// behaves like IronMQ. Run it from a test in the package that implements the Client:
+//
+//  func TestConformance(t *testing.T) {
+//    mqconformance.RunSuite(t, func() mq.Client {
+//      return NewMyClient(mq.NewMemClient())
+//    })
+//  }
+//
+// One test waits for a reservation to expire, which takes at least mq.MinTimeout seconds.
+// It runs in parallel with the other tests and is skipped when go test runs with -short,
+// unless the Suite has a Clock. Clients that are backed by MemClients that use the
+// Suite's Clock run every test instantly:
+//
+//  clock := mq.NewFakeClock(time.Now())
+//  mqconformance.Suite{
+//    NewClient: func() mq.Client {
+//      return NewMyClient(mq.NewMemClient(mq.WithClock(clock)))
+//    },
+//    Clock: clock,
+//  }.Run(t)
+package mqconformance
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	"github.com/arschles/gorion/mq"
+	"golang.org/x/net/context"
+)
+
+// Suite is the conformance test suite. RunSuite runs it with the default settings
+type Suite struct {
+	// NewClient returns the Client to test. It's called once for each test, and the
+	// queues that each test uses have names that no other test uses
+	NewClient func() mq.Client
+	// Token is passed to every Client func. Leave it empty to use the client's default token
+	Token string
+	// ProjectID is passed to every Client func. Leave it empty to use the client's default project ID
+	ProjectID string
+	// Clock is the clock that the Clients measure time with. If it's non-nil, tests advance it
+	// instead of sleeping, and don't run in parallel
+	Clock *mq.FakeClock
+}
+
+// RunSuite runs the conformance suite against the Clients that newClient returns, passing
+// empty tokens and project IDs to every Client func
+func RunSuite(t *testing.T, newClient func() mq.Client) {
+	Suite{NewClient: newClient}.Run(t)
+}
+
+// Run runs every test in the suite as a subtest of t. It returns after every test has finished,
+// including the parallel ones, so callers can defer cleanup of anything that NewClient uses
+func (s Suite) Run(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, *env)
+	}{
+		{"Ordering", testOrdering},
+		{"Delay", testDelay},
+		{"DequeueNum", testDequeueNum},
+		{"ReservationExpiry", testReservationExpiry},
+		{"TouchRelease", testTouchRelease},
+		{"DeleteReserved", testDeleteReserved},
+		{"DequeueDelete", testDequeueDelete},
+		{"DeleteReservedBatch", testDeleteReservedBatch},
+		{"QueueLifecycle", testQueueLifecycle},
+		{"ErrorSentinels", testErrorSentinels},
+		{"ConcurrentConsumers", testConcurrentConsumers},
+		{"ContextCancellation", testContextCancellation},
+	}
+	// parallel subtests don't finish until their parent does, so group them under one parent
+	t.Run("group", func(t *testing.T) {
+		for _, test := range tests {
+			test := test
+			t.Run(test.name, func(t *testing.T) {
+				test.fn(t, &env{s: s, cl: s.NewClient(), qName: "conformance-" + test.name})
+			})
+		}
+	})
+}
+
+// env is the environment that a single test runs in
+type env struct {
+	s     Suite
+	cl    mq.Client
+	qName string
+}
+
+// enqueue enqueues a message with each of bodies onto the test's queue, and returns their IDs
+func (e *env) enqueue(t *testing.T, delay uint32, bodies ...string) []string {
+	msgs := make([]mq.NewMessage, len(bodies))
+	for i, body := range bodies {
+		msgs[i] = mq.NewMessage{Body: body, Delay: delay, PushHeaders: make(map[string]string)}
+	}
+	enq, err := e.cl.Enqueue(context.Background(), e.s.Token, e.s.ProjectID, e.qName, msgs)
+	assert.NoErr(t, err)
+	assert.Equal(t, len(bodies), len(enq.IDs), "number of enqueued message IDs")
+	return enq.IDs
+}
+
+// dequeue dequeues at most num messages from the test's queue
+func (e *env) dequeue(t *testing.T, num int, wait mq.Wait, delete bool) []mq.DequeuedMessage {
+	msgs, err := e.cl.Dequeue(context.Background(), e.s.Token, e.s.ProjectID, e.qName, num, mq.Timeout(mq.MinTimeout), wait, delete)
+	assert.NoErr(t, err)
+	return msgs
+}
+
+// sleep waits for d to pass on the Clients' clock
+func (e *env) sleep(d time.Duration) {
+	if e.s.Clock != nil {
+		e.s.Clock.Advance(d)
+		return
+	}
+	time.Sleep(d)
+}
+
+// size returns the number of messages that are waiting to be dequeued from the test's queue
+func (e *env) size(t *testing.T) int {
+	msgs, err := e.cl.Peek(context.Background(), e.s.Token, e.s.ProjectID, e.qName, mq.MaxPerPage)
+	assert.NoErr(t, err)
+	return len(msgs)
+}
+
+// bodies returns the bodies of msgs
+func bodies(msgs []mq.DequeuedMessage) []string {
+	ret := make([]string, len(msgs))
+	for i, msg := range msgs {
+		ret[i] = msg.Body
+	}
+	return ret
+}
+
+func testOrdering(t *testing.T, e *env) {
+	expected := []string{"1", "2", "3", "4", "5"}
+	ids := e.enqueue(t, 0, expected...)
+	msgs := e.dequeue(t, len(expected), 0, false)
+	assert.Equal(t, expected, bodies(msgs), "dequeued message bodies")
+	for i, msg := range msgs {
+		assert.Equal(t, ids[i], msg.ID, fmt.Sprintf("ID of message %d", i))
+		assert.Equal(t, 1, msg.ReservedCount, fmt.Sprintf("reserved count of message %d", i))
+		assert.True(t, msg.ReservationID != "", "message %d has no reservation ID", i)
+	}
+}
+
+func testDelay(t *testing.T, e *env) {
+	e.enqueue(t, 1, "delayed")
+	assert.Equal(t, 0, len(e.dequeue(t, 1, 0, false)), "number of messages dequeued before the delay")
+	e.sleep(2 * time.Second)
+	msgs := e.dequeue(t, 1, 0, false)
+	assert.Equal(t, []string{"delayed"}, bodies(msgs), "message bodies dequeued after the delay")
+}
+
+func testDequeueNum(t *testing.T, e *env) {
+	e.enqueue(t, 0, "1", "2", "3")
+	start := time.Now()
+	msgs := e.dequeue(t, 2, mq.MaxWait, false)
+	assert.True(t, time.Since(start) < mq.MaxWait*time.Second/2, "dequeue took %s with enough messages available", time.Since(start))
+	assert.Equal(t, []string{"1", "2"}, bodies(msgs), "dequeued message bodies")
+	assert.Equal(t, 1, e.size(t), "number of messages left")
+}
+
+func testReservationExpiry(t *testing.T, e *env) {
+	if e.s.Clock == nil {
+		if testing.Short() {
+			t.Skipf("skipping reservation expiry test, which takes %d seconds, in short mode", mq.MinTimeout)
+		}
+		t.Parallel()
+	}
+	e.enqueue(t, 0, "abc")
+	msgs := e.dequeue(t, 1, 0, false)
+	assert.Equal(t, 1, len(msgs), "number of dequeued messages")
+	assert.Equal(t, 0, e.size(t), "number of messages while reserved")
+	e.sleep((mq.MinTimeout + 2) * time.Second)
+	redelivered := e.dequeue(t, 1, 0, false)
+	assert.Equal(t, 1, len(redelivered), "number of messages dequeued after the reservation expired")
+	assert.Equal(t, msgs[0].ID, redelivered[0].ID, "redelivered message ID")
+	assert.Equal(t, 2, redelivered[0].ReservedCount, "redelivered message reserved count")
+	ctx := context.Background()
+	_, err := e.cl.DeleteReserved(ctx, e.s.Token, e.s.ProjectID, e.qName, msgs[0].ID, msgs[0].ReservationID)
+	assert.Err(t, mq.ErrNoSuchReservation, err)
+	_, err = e.cl.DeleteReserved(ctx, e.s.Token, e.s.ProjectID, e.qName, redelivered[0].ID, redelivered[0].ReservationID)
+	assert.NoErr(t, err)
+}
+
+func testTouchRelease(t *testing.T, e *env) {
+	ctx := context.Background()
+	e.enqueue(t, 0, "abc")
+	msgs := e.dequeue(t, 1, 0, false)
+	assert.Equal(t, 1, len(msgs), "number of dequeued messages")
+	touched, err := e.cl.Touch(ctx, e.s.Token, e.s.ProjectID, e.qName, msgs[0].ID, msgs[0].ReservationID, mq.Timeout(mq.MinTimeout))
+	assert.NoErr(t, err)
+	assert.True(t, touched.ReservationID != "", "touch returned no reservation ID")
+	_, err = e.cl.Release(ctx, e.s.Token, e.s.ProjectID, e.qName, msgs[0].ID, touched.ReservationID, 0)
+	assert.NoErr(t, err)
+	redelivered := e.dequeue(t, 1, 0, false)
+	assert.Equal(t, 1, len(redelivered), "number of messages dequeued after release")
+	assert.Equal(t, msgs[0].ID, redelivered[0].ID, "released message ID")
+}
+
+func testDeleteReserved(t *testing.T, e *env) {
+	ctx := context.Background()
+	e.enqueue(t, 0, "abc")
+	msgs := e.dequeue(t, 1, 0, false)
+	assert.Equal(t, 1, len(msgs), "number of dequeued messages")
+	_, err := e.cl.DeleteReserved(ctx, e.s.Token, e.s.ProjectID, e.qName, msgs[0].ID, msgs[0].ReservationID)
+	assert.NoErr(t, err)
+	_, err = e.cl.DeleteReserved(ctx, e.s.Token, e.s.ProjectID, e.qName, msgs[0].ID, msgs[0].ReservationID)
+	assert.Err(t, mq.ErrNoSuchReservation, err)
+	_, err = e.cl.GetMessage(ctx, e.s.Token, e.s.ProjectID, e.qName, msgs[0].ID)
+	assert.Err(t, mq.ErrNoSuchMessage, err)
+	_, err = e.cl.Release(ctx, e.s.Token, e.s.ProjectID, e.qName, msgs[0].ID, msgs[0].ReservationID, 0)
+	assert.Err(t, mq.ErrNoSuchReservation, err)
+	assert.Equal(t, 0, e.size(t), "number of messages after delete")
+}
+
+func testDequeueDelete(t *testing.T, e *env) {
+	ctx := context.Background()
+	e.enqueue(t, 0, "abc")
+	msgs := e.dequeue(t, 1, 0, true)
+	assert.Equal(t, 1, len(msgs), "number of dequeued messages")
+	_, err := e.cl.GetMessage(ctx, e.s.Token, e.s.ProjectID, e.qName, msgs[0].ID)
+	assert.Err(t, mq.ErrNoSuchMessage, err)
+	info, err := e.cl.GetQueue(ctx, e.s.Token, e.s.ProjectID, e.qName)
+	assert.NoErr(t, err)
+	assert.Equal(t, 0, info.Size, "queue size")
+	assert.Equal(t, 1, info.TotalMessages, "total messages")
+}
+
+func testDeleteReservedBatch(t *testing.T, e *env) {
+	ctx := context.Background()
+	e.enqueue(t, 0, "1", "2")
+	msgs := e.dequeue(t, 2, 0, false)
+	assert.Equal(t, 2, len(msgs), "number of dequeued messages")
+	batch := []mq.ReservedMessage{
+		{ID: msgs[0].ID, ReservationID: msgs[0].ReservationID},
+		{ID: msgs[1].ID, ReservationID: "not-a-reservation"},
+	}
+	deleted, err := e.cl.DeleteReservedBatch(ctx, e.s.Token, e.s.ProjectID, e.qName, batch)
+	assert.NoErr(t, err)
+	assert.Equal(t, 2, len(deleted.Results), "number of delete results")
+	assert.NoErr(t, deleted.Results[0].Err)
+	assert.Err(t, mq.ErrNoSuchReservation, deleted.Results[1].Err)
+	_, err = e.cl.GetMessage(ctx, e.s.Token, e.s.ProjectID, e.qName, msgs[1].ID)
+	assert.NoErr(t, err)
+}
+
+func testQueueLifecycle(t *testing.T, e *env) {
+	ctx := context.Background()
+	info, err := e.cl.CreateQueue(ctx, e.s.Token, e.s.ProjectID, e.qName, mq.QueueConfig{MessageTimeout: 120})
+	assert.NoErr(t, err)
+	assert.Equal(t, e.qName, info.Name, "created queue name")
+	assert.Equal(t, uint32(120), info.MessageTimeout, "created queue message timeout")
+	info, err = e.cl.UpdateQueue(ctx, e.s.Token, e.s.ProjectID, e.qName, mq.QueueConfig{MessageExpiration: 3600})
+	assert.NoErr(t, err)
+	assert.Equal(t, uint32(120), info.MessageTimeout, "updated queue message timeout")
+	assert.Equal(t, uint32(3600), info.MessageExpiration, "updated queue message expiration")
+	e.enqueue(t, 0, "1", "2")
+	_, err = e.cl.ClearQueue(ctx, e.s.Token, e.s.ProjectID, e.qName)
+	assert.NoErr(t, err)
+	assert.Equal(t, 0, e.size(t), "number of messages after clear")
+	queues, err := e.cl.ListQueues(ctx, e.s.Token, e.s.ProjectID, e.qName, "", 0)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(queues), "number of listed queues")
+	_, err = e.cl.DeleteQueue(ctx, e.s.Token, e.s.ProjectID, e.qName)
+	assert.NoErr(t, err)
+	_, err = e.cl.GetQueue(ctx, e.s.Token, e.s.ProjectID, e.qName)
+	assert.Err(t, mq.ErrNoSuchQueue, err)
+}
+
+func testErrorSentinels(t *testing.T, e *env) {
+	ctx := context.Background()
+	tok, proj := e.s.Token, e.s.ProjectID
+	_, err := e.cl.GetQueue(ctx, tok, proj, e.qName)
+	assert.Err(t, mq.ErrNoSuchQueue, err)
+	_, err = e.cl.DeleteQueue(ctx, tok, proj, e.qName)
+	assert.Err(t, mq.ErrNoSuchQueue, err)
+	_, err = e.cl.CreateQueue(ctx, tok, proj, e.qName, mq.QueueConfig{})
+	assert.NoErr(t, err)
+	_, err = e.cl.CreateQueue(ctx, tok, proj, e.qName, mq.QueueConfig{})
+	assert.Err(t, mq.ErrQueueExists, err)
+	_, err = e.cl.CreateQueue(ctx, tok, proj, e.qName+"-bad", mq.QueueConfig{Type: "bogus"})
+	assert.Err(t, mq.ErrInvalidQueueType, err)
+	_, err = e.cl.GetMessage(ctx, tok, proj, e.qName, "12345")
+	assert.Err(t, mq.ErrNoSuchMessage, err)
+	_, err = e.cl.Touch(ctx, tok, proj, e.qName, "12345", "not-a-reservation", 0)
+	assert.Err(t, mq.ErrNoSuchReservation, err)
+	_, err = e.cl.Dequeue(ctx, tok, proj, e.qName, 1, mq.Timeout(mq.MinTimeout-1), 0, false)
+	assert.Err(t, mq.ErrTimeoutOutOfRange, err)
+	_, err = e.cl.Dequeue(ctx, tok, proj, e.qName, 1, mq.Timeout(mq.MinTimeout), mq.MaxWait+1, false)
+	assert.Err(t, mq.ErrWaitOutOfRange, err)
+	_, err = e.cl.ListQueues(ctx, tok, proj, "", "", mq.MaxPerPage+1)
+	assert.Err(t, mq.ErrPerPageOutOfRange, err)
+	_, err = e.cl.AddSubscribers(ctx, tok, proj, e.qName, []mq.Subscriber{{Name: "a", URL: "http://localhost:1"}})
+	assert.Err(t, mq.ErrNotPushQueue, err)
+	_, err = e.cl.DeleteAlert(ctx, tok, proj, e.qName, "not-an-alert")
+	assert.Err(t, mq.ErrNoSuchAlert, err)
+}
+
+func testConcurrentConsumers(t *testing.T, e *env) {
+	const numProducers, numPerProducer, numConsumers = 4, 10, 4
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for p := 0; p < numProducers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < numPerProducer; i++ {
+				body := strconv.Itoa(p*numPerProducer + i)
+				msgs := []mq.NewMessage{{Body: body, PushHeaders: make(map[string]string)}}
+				if _, err := e.cl.Enqueue(ctx, e.s.Token, e.s.ProjectID, e.qName, msgs); err != nil {
+					t.Errorf("enqueue error (%s)", err)
+				}
+			}
+		}(p)
+	}
+
+	// consumers don't long-poll, so that the test also works with a Clock that doesn't move
+	const total = numProducers * numPerProducer
+	deadline := time.Now().Add(10 * time.Second)
+	var mtx sync.Mutex
+	var received []string
+	for c := 0; c < numConsumers; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				mtx.Lock()
+				done := len(received) >= total
+				mtx.Unlock()
+				if done {
+					return
+				}
+				msgs, err := e.cl.Dequeue(ctx, e.s.Token, e.s.ProjectID, e.qName, 3, mq.Timeout(mq.MinTimeout), 0, true)
+				if err != nil {
+					t.Errorf("dequeue error (%s)", err)
+					return
+				}
+				if len(msgs) == 0 {
+					time.Sleep(10 * time.Millisecond)
+					continue
+				}
+				mtx.Lock()
+				received = append(received, bodies(msgs)...)
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Strings(received)
+	expected := make([]string, total)
+	for i := range expected {
+		expected[i] = strconv.Itoa(i)
+	}
+	sort.Strings(expected)
+	assert.Equal(t, expected, received, "received message bodies")
+}
+
+func testContextCancellation(t *testing.T, e *env) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	msgs := []mq.NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}}
+	_, err := e.cl.Enqueue(ctx, e.s.Token, e.s.ProjectID, e.qName, msgs)
+	assert.True(t, err != nil, "enqueue with a cancelled context returned no error")
+	_, err = e.cl.GetQueue(ctx, e.s.Token, e.s.ProjectID, e.qName)
+	assert.True(t, err != nil, "get queue with a cancelled context returned no error")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = e.cl.Dequeue(ctx, e.s.Token, e.s.ProjectID, e.qName+"-empty", 1, mq.Timeout(mq.MinTimeout), mq.MaxWait, false)
+	assert.True(t, err != nil, "dequeue that outlived its context returned no error")
+	assert.True(t, time.Since(start) < mq.MaxWait*time.Second/2, "dequeue took %s after its context was done", time.Since(start))
+}
@@ -0,0 +1,45 @@
+package mqconformance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/arschles/gorion/mq"
+	"github.com/arschles/gorion/mq/mqtest"
+)
+
+func TestMemClient(t *testing.T) {
+	t.Parallel()
+	RunSuite(t, func() mq.Client {
+		return mq.NewMemClient()
+	})
+}
+
+func TestMemClientFakeClock(t *testing.T) {
+	clock := mq.NewFakeClock(time.Now())
+	Suite{
+		NewClient: func() mq.Client {
+			return mq.NewMemClient(mq.WithClock(clock))
+		},
+		Clock: clock,
+	}.Run(t)
+}
+
+func TestHTTPClient(t *testing.T) {
+	t.Parallel()
+	clock := mq.NewFakeClock(time.Now())
+	var servers []*mqtest.Server
+	defer func() {
+		for _, srv := range servers {
+			srv.Close()
+		}
+	}()
+	Suite{
+		NewClient: func() mq.Client {
+			srv := mqtest.NewServer("test-token", "test-proj", mq.WithClock(clock))
+			servers = append(servers, srv)
+			return srv.Client()
+		},
+		Clock: clock,
+	}.Run(t)
+}
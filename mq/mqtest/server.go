@@ -0,0 +1,451 @@
+// Package mqtest provides an in-process IronMQ v3 API server for testing code that uses
+// mq.HTTPClient. The server is backed by an mq.MemClient, so tests can set up and inspect
+// queues directly while the code under test talks HTTP to them.
+//
+// Example usage:
+//
+//  func TestWorker(t *testing.T) {
+//    srv := mqtest.NewServer("my-token", "my-project")
+//    defer srv.Close()
+//    runWorker(srv.Client())
+//  }
+package mqtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/arschles/gorion"
+	"github.com/arschles/gorion/mq"
+	"github.com/gorilla/mux"
+)
+
+// Server is an IronMQ v3 API server that runs in the current process. Create one with NewServer
+type Server struct {
+	// Mem holds the server's queues. Use it to set up and inspect queues without going through HTTP
+	Mem *mq.MemClient
+	// Token is the OAuth token that the server accepts
+	Token string
+	// ProjectID is the ID of the only project that the server serves
+	ProjectID string
+	srv       *httptest.Server
+}
+
+// NewServer starts and returns a new Server that accepts requests with token for the
+// project with ID projID. opts configure the server's MemClient. Callers must call Close
+// when they're done with the server
+func NewServer(token, projID string, opts ...mq.MemOption) *Server {
+	mem := mq.NewMemClient(opts...)
+	return &Server{
+		Mem:       mem,
+		Token:     token,
+		ProjectID: projID,
+		srv:       httptest.NewServer(NewHandler(mem, token, projID)),
+	}
+}
+
+// URL returns the base URL of s, in the form http://ip:port
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Close shuts down s and blocks until all of its outstanding requests have finished
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client returns an mq.HTTPClient that talks to s. Its funcs use s.Token and s.ProjectID
+// when they're passed an empty token or project ID. opts are passed to mq.NewHTTPClient
+func (s *Server) Client(opts ...mq.HTTPOption) *mq.HTTPClient {
+	u, err := url.Parse(s.srv.URL)
+	if err != nil {
+		panic(fmt.Sprintf("mqtest: invalid server URL [%s] (%s)", s.srv.URL, err))
+	}
+	port, err := strconv.ParseUint(u.Port(), 10, 16)
+	if err != nil {
+		panic(fmt.Sprintf("mqtest: invalid server port [%s] (%s)", u.Port(), err))
+	}
+	conf := gorion.Config{
+		Token:      s.Token,
+		ProjectID:  s.ProjectID,
+		Scheme:     mq.SchemeHTTP,
+		Host:       u.Hostname(),
+		Port:       uint16(port),
+		APIVersion: "3",
+	}
+	cl, err := mq.NewHTTPClientFromConfig(conf, opts...)
+	if err != nil {
+		panic(fmt.Sprintf("mqtest: creating client (%s)", err))
+	}
+	return cl
+}
+
+// NewHandler returns an http.Handler that serves the IronMQ v3 API from mem. It only
+// serves requests for the project with ID projID that have token in their Authorization
+// header (as "OAuth {token}") or oauth query parameter. Errors are returned as IronMQ
+// returns them, as JSON objects with a msg field
+func NewHandler(mem *mq.MemClient, token, projID string) http.Handler {
+	h := &handler{mem: mem, token: token, projID: projID}
+	r := mux.NewRouter()
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeMsg(w, http.StatusNotFound, fmt.Sprintf("path %s not found", r.URL.Path))
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeMsg(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed on %s", r.Method, r.URL.Path))
+	})
+	p := r.PathPrefix("/3/projects/{project_id}").Subrouter()
+	p.HandleFunc("/queues", h.listQueues).Methods("GET")
+	p.HandleFunc("/queues/{queue_name}", h.putQueue).Methods("PUT", "PATCH")
+	p.HandleFunc("/queues/{queue_name}", h.getQueue).Methods("GET")
+	p.HandleFunc("/queues/{queue_name}", h.deleteQueue).Methods("DELETE")
+	p.HandleFunc("/queues/{queue_name}/messages", h.enqueue).Methods("POST")
+	p.HandleFunc("/queues/{queue_name}/messages", h.peek).Methods("GET")
+	p.HandleFunc("/queues/{queue_name}/messages", h.deleteMessages).Methods("DELETE")
+	p.HandleFunc("/queues/{queue_name}/webhook", h.webhook).Methods("POST")
+	p.HandleFunc("/queues/{queue_name}/reservations", h.dequeue).Methods("POST")
+	p.HandleFunc("/queues/{queue_name}/messages/{message_id}", h.getMessage).Methods("GET")
+	p.HandleFunc("/queues/{queue_name}/messages/{message_id}", h.deleteReserved).Methods("DELETE")
+	p.HandleFunc("/queues/{queue_name}/messages/{message_id}/touch", h.touch).Methods("POST")
+	p.HandleFunc("/queues/{queue_name}/messages/{message_id}/release", h.release).Methods("POST")
+	p.HandleFunc("/queues/{queue_name}/messages/{message_id}/subscribers", h.getPushStatuses).Methods("GET")
+	p.HandleFunc("/queues/{queue_name}/subscribers", h.subscribers).Methods("POST", "PUT", "DELETE")
+	p.HandleFunc("/queues/{queue_name}/alerts", h.alerts).Methods("POST", "PUT")
+	p.HandleFunc("/queues/{queue_name}/alerts/{alert_id}", h.deleteAlert).Methods("DELETE")
+	return h.authorize(r)
+}
+
+type handler struct {
+	mem    *mq.MemClient
+	token  string
+	projID string
+}
+
+// errMsg is the body of every error response
+type errMsg struct {
+	Msg string `json:"msg"`
+}
+
+// writeMsg writes msg to w as an IronMQ error with the given status code
+func writeMsg(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(errMsg{Msg: msg})
+}
+
+// writeErr writes err to w as an IronMQ error, with the status code that IronMQ uses for err
+func writeErr(w http.ResponseWriter, err error) {
+	writeMsg(w, statusCode(err), err.Error())
+}
+
+// statusCode returns the HTTP status code that IronMQ responds with for err
+func statusCode(err error) int {
+	switch err {
+	case mq.ErrNoSuchQueue, mq.ErrNoSuchMessage, mq.ErrNoSuchReservation, mq.ErrNoSuchAlert:
+		return http.StatusNotFound
+	case mq.ErrQueueExists:
+		return http.StatusConflict
+	case mq.ErrTimeoutOutOfRange, mq.ErrWaitOutOfRange, mq.ErrDelayOutOfRange, mq.ErrExpirationOutOfRange,
+		mq.ErrPerPageOutOfRange, mq.ErrInvalidQueueType, mq.ErrNoSubscribers, mq.ErrInvalidSubscriber,
+		mq.ErrNotPushQueue, mq.ErrInvalidAlert:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJSON writes v to w as JSON
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		writeMsg(w, http.StatusInternalServerError, fmt.Sprintf("encoding response json (%s)", err))
+	}
+}
+
+// writeResult writes v to w as JSON if err is nil, and err as an IronMQ error otherwise
+func writeResult(w http.ResponseWriter, v interface{}, err error) {
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, v)
+}
+
+// decode decodes the JSON body of r into v. Writes an error to w and returns false if it can't
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeMsg(w, http.StatusBadRequest, fmt.Sprintf("invalid json (%s)", err))
+		return false
+	}
+	return true
+}
+
+// intParam returns the int value of the query parameter called name, or 0 if it's missing.
+// Writes an error to w and returns false if it's not an int
+func intParam(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	str := r.URL.Query().Get(name)
+	if str == "" {
+		return 0, true
+	}
+	ret, err := strconv.Atoi(str)
+	if err != nil {
+		writeMsg(w, http.StatusBadRequest, fmt.Sprintf("%s must be an int", name))
+		return 0, false
+	}
+	return ret, true
+}
+
+// authorize wraps next so that requests without the right token or project ID are rejected
+func (h *handler) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("oauth")
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			token = strings.TrimSpace(strings.TrimPrefix(auth, "OAuth"))
+		}
+		if token != h.token {
+			writeMsg(w, http.StatusUnauthorized, "Invalid authentication: The OAuth token is either not provided or invalid")
+			return
+		}
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if len(parts) >= 3 && parts[1] == "projects" && parts[2] != h.projID {
+			writeMsg(w, http.StatusForbidden, fmt.Sprintf("project [%s] not found or not accessible with this token", parts[2]))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *handler) listQueues(w http.ResponseWriter, r *http.Request) {
+	perPage, ok := intParam(w, r, "per_page")
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	queues, err := h.mem.ListQueues(r.Context(), h.token, h.projID, query.Get("prefix"), query.Get("previous"), perPage)
+	writeResult(w, struct {
+		Queues []mq.QueueInfo `json:"queues"`
+	}{Queues: queues}, err)
+}
+
+type queueBody struct {
+	Queue mq.QueueConfig `json:"queue"`
+}
+
+type queueInfoBody struct {
+	Queue *mq.QueueInfo `json:"queue"`
+}
+
+func (h *handler) putQueue(w http.ResponseWriter, r *http.Request) {
+	req := new(queueBody)
+	if !decode(w, r, req) {
+		return
+	}
+	qName := mux.Vars(r)["queue_name"]
+	var info *mq.QueueInfo
+	var err error
+	if r.Method == "PUT" {
+		info, err = h.mem.CreateQueue(r.Context(), h.token, h.projID, qName, req.Queue)
+	} else {
+		info, err = h.mem.UpdateQueue(r.Context(), h.token, h.projID, qName, req.Queue)
+	}
+	writeResult(w, queueInfoBody{Queue: info}, err)
+}
+
+func (h *handler) getQueue(w http.ResponseWriter, r *http.Request) {
+	info, err := h.mem.GetQueue(r.Context(), h.token, h.projID, mux.Vars(r)["queue_name"])
+	writeResult(w, queueInfoBody{Queue: info}, err)
+}
+
+func (h *handler) deleteQueue(w http.ResponseWriter, r *http.Request) {
+	ret, err := h.mem.DeleteQueue(r.Context(), h.token, h.projID, mux.Vars(r)["queue_name"])
+	writeResult(w, ret, err)
+}
+
+func (h *handler) enqueue(w http.ResponseWriter, r *http.Request) {
+	req := new(struct {
+		Messages []mq.NewMessage `json:"messages"`
+	})
+	if !decode(w, r, req) {
+		return
+	}
+	ret, err := h.mem.Enqueue(r.Context(), h.token, h.projID, mux.Vars(r)["queue_name"], req.Messages)
+	writeResult(w, ret, err)
+}
+
+func (h *handler) webhook(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	ret, err := h.mem.PostWebhook(r.Context(), h.token, h.projID, mux.Vars(r)["queue_name"], r.Body)
+	writeResult(w, ret, err)
+}
+
+func (h *handler) peek(w http.ResponseWriter, r *http.Request) {
+	num, ok := intParam(w, r, "n")
+	if !ok {
+		return
+	}
+	msgs, err := h.mem.Peek(r.Context(), h.token, h.projID, mux.Vars(r)["queue_name"], num)
+	writeResult(w, struct {
+		Messages []mq.Message `json:"messages"`
+	}{Messages: msgs}, err)
+}
+
+// deleteMessages serves both batch deletes and clears, which share a route. Clears have an
+// empty body or no ids key in the body. A request with an ids key is a batch delete, even
+// if the list of ids is empty or null
+func (h *handler) deleteMessages(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		writeMsg(w, http.StatusBadRequest, fmt.Sprintf("error reading body (%s)", err))
+		return
+	}
+	req := make(map[string]json.RawMessage)
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeMsg(w, http.StatusBadRequest, fmt.Sprintf("invalid json (%s)", err))
+			return
+		}
+	}
+	qName := mux.Vars(r)["queue_name"]
+	rawIDs, ok := req["ids"]
+	if !ok {
+		ret, err := h.mem.ClearQueue(r.Context(), h.token, h.projID, qName)
+		writeResult(w, ret, err)
+		return
+	}
+	var ids []mq.ReservedMessage
+	if err := json.Unmarshal(rawIDs, &ids); err != nil {
+		writeMsg(w, http.StatusBadRequest, fmt.Sprintf("invalid ids (%s)", err))
+		return
+	}
+	ret, err := h.mem.DeleteReservedBatch(r.Context(), h.token, h.projID, qName, ids)
+	writeResult(w, ret, err)
+}
+
+func (h *handler) dequeue(w http.ResponseWriter, r *http.Request) {
+	req := new(struct {
+		Num     int  `json:"n"`
+		Timeout int  `json:"timeout"`
+		Wait    int  `json:"wait"`
+		Delete  bool `json:"delete"`
+	})
+	if !decode(w, r, req) {
+		return
+	}
+	qName := mux.Vars(r)["queue_name"]
+	msgs, err := h.mem.Dequeue(r.Context(), h.token, h.projID, qName, req.Num, mq.Timeout(req.Timeout), mq.Wait(req.Wait), req.Delete)
+	if msgs == nil {
+		msgs = []mq.DequeuedMessage{}
+	}
+	writeResult(w, struct {
+		Messages []mq.DequeuedMessage `json:"messages"`
+	}{Messages: msgs}, err)
+}
+
+func (h *handler) getMessage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	msg, err := h.mem.GetMessage(r.Context(), h.token, h.projID, vars["queue_name"], vars["message_id"])
+	writeResult(w, struct {
+		Message *mq.Message `json:"message"`
+	}{Message: msg}, err)
+}
+
+type reservationBody struct {
+	ReservationID string `json:"reservation_id"`
+	Timeout       int    `json:"timeout"`
+	Delay         uint32 `json:"delay"`
+}
+
+func (h *handler) deleteReserved(w http.ResponseWriter, r *http.Request) {
+	req := new(reservationBody)
+	if !decode(w, r, req) {
+		return
+	}
+	vars := mux.Vars(r)
+	ret, err := h.mem.DeleteReserved(r.Context(), h.token, h.projID, vars["queue_name"], vars["message_id"], req.ReservationID)
+	writeResult(w, ret, err)
+}
+
+func (h *handler) touch(w http.ResponseWriter, r *http.Request) {
+	req := new(reservationBody)
+	if !decode(w, r, req) {
+		return
+	}
+	vars := mux.Vars(r)
+	ret, err := h.mem.Touch(r.Context(), h.token, h.projID, vars["queue_name"], vars["message_id"], req.ReservationID, mq.Timeout(req.Timeout))
+	writeResult(w, ret, err)
+}
+
+func (h *handler) release(w http.ResponseWriter, r *http.Request) {
+	req := new(reservationBody)
+	if !decode(w, r, req) {
+		return
+	}
+	vars := mux.Vars(r)
+	ret, err := h.mem.Release(r.Context(), h.token, h.projID, vars["queue_name"], vars["message_id"], req.ReservationID, req.Delay)
+	writeResult(w, ret, err)
+}
+
+func (h *handler) getPushStatuses(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	statuses, err := h.mem.GetPushStatuses(r.Context(), h.token, h.projID, vars["queue_name"], vars["message_id"])
+	writeResult(w, struct {
+		Subscribers []mq.PushStatus `json:"subscribers"`
+	}{Subscribers: statuses}, err)
+}
+
+func (h *handler) subscribers(w http.ResponseWriter, r *http.Request) {
+	req := new(struct {
+		Subscribers []mq.Subscriber `json:"subscribers"`
+	})
+	if !decode(w, r, req) {
+		return
+	}
+	qName := mux.Vars(r)["queue_name"]
+	var ret *mq.Updated
+	var err error
+	switch r.Method {
+	case "POST":
+		ret, err = h.mem.AddSubscribers(r.Context(), h.token, h.projID, qName, req.Subscribers)
+	case "PUT":
+		ret, err = h.mem.ReplaceSubscribers(r.Context(), h.token, h.projID, qName, req.Subscribers)
+	default:
+		names := make([]string, len(req.Subscribers))
+		for i, sub := range req.Subscribers {
+			names[i] = sub.Name
+		}
+		ret, err = h.mem.RemoveSubscribers(r.Context(), h.token, h.projID, qName, names)
+	}
+	writeResult(w, ret, err)
+}
+
+func (h *handler) alerts(w http.ResponseWriter, r *http.Request) {
+	req := new(struct {
+		Alerts []mq.Alert `json:"alerts"`
+	})
+	if !decode(w, r, req) {
+		return
+	}
+	qName := mux.Vars(r)["queue_name"]
+	var ret *mq.Updated
+	var err error
+	if r.Method == "PUT" {
+		ret, err = h.mem.ReplaceAlerts(r.Context(), h.token, h.projID, qName, req.Alerts)
+	} else {
+		ret, err = h.mem.AddAlerts(r.Context(), h.token, h.projID, qName, req.Alerts)
+	}
+	writeResult(w, ret, err)
+}
+
+func (h *handler) deleteAlert(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ret, err := h.mem.DeleteAlert(r.Context(), h.token, h.projID, vars["queue_name"], vars["alert_id"])
+	writeResult(w, ret, err)
+}
@@ -0,0 +1,159 @@
+package mqtest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/arschles/assert"
+	"github.com/arschles/gorion/mq"
+	"golang.org/x/net/context"
+)
+
+const (
+	token  = "test-token"
+	projID = "test-proj"
+	qName  = "test-queue"
+)
+
+func TestServerMessageLifecycle(t *testing.T) {
+	srv := NewServer(token, projID)
+	defer srv.Close()
+	cl := srv.Client()
+	ctx := context.Background()
+	_, err := cl.CreateQueue(ctx, "", "", qName, mq.QueueConfig{MessageTimeout: 60})
+	assert.NoErr(t, err)
+	_, err = cl.CreateQueue(ctx, "", "", qName, mq.QueueConfig{})
+	assert.Err(t, mq.ErrQueueExists, err)
+	enq, err := cl.Enqueue(ctx, "", "", qName, []mq.NewMessage{{Body: "abc", PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(enq.IDs), "number of enqueued messages")
+
+	// the server's MemClient holds the same queues
+	info, err := srv.Mem.GetQueue(ctx, token, projID, qName)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, info.Size, "queue size")
+
+	msgs, err := cl.Dequeue(ctx, "", "", qName, 1, mq.Timeout(30), mq.Wait(0), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(msgs), "number of dequeued messages")
+	assert.Equal(t, enq.IDs[0], msgs[0].ID, "dequeued message ID")
+	_, err = cl.DeleteReserved(ctx, "", "", qName, msgs[0].ID, "bogus")
+	assert.Err(t, mq.ErrNoSuchReservation, err)
+	_, err = cl.DeleteReserved(ctx, "", "", qName, msgs[0].ID, msgs[0].ReservationID)
+	assert.NoErr(t, err)
+	_, err = cl.GetMessage(ctx, "", "", qName, msgs[0].ID)
+	assert.Err(t, mq.ErrNoSuchMessage, err)
+
+	_, err = cl.PostWebhook(ctx, "", "", qName, strings.NewReader("raw body"))
+	assert.NoErr(t, err)
+	msgs, err = cl.Dequeue(ctx, "", "", qName, 1, mq.Timeout(30), mq.Wait(0), true)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(msgs), "number of dequeued webhook messages")
+	assert.Equal(t, "raw body", msgs[0].Body, "webhook message body")
+	peeked, err := cl.Peek(ctx, "", "", qName, 1)
+	assert.NoErr(t, err)
+	assert.Equal(t, 0, len(peeked), "number of messages after dequeuing with delete")
+
+	_, err = cl.DeleteQueue(ctx, "", "", qName)
+	assert.NoErr(t, err)
+	_, err = cl.GetQueue(ctx, "", "", qName)
+	assert.Err(t, mq.ErrNoSuchQueue, err)
+}
+
+func TestServerQueueConfig(t *testing.T) {
+	srv := NewServer(token, projID)
+	defer srv.Close()
+	cl := srv.Client()
+	ctx := context.Background()
+	conf := mq.QueueConfig{
+		Type: mq.QueueTypeMulticast,
+		Push: &mq.PushConfig{Subscribers: []mq.Subscriber{{Name: "a", URL: "http://localhost:1"}}},
+	}
+	_, err := cl.CreateQueue(ctx, "", "", qName, conf)
+	assert.NoErr(t, err)
+	_, err = cl.AddSubscribers(ctx, "", "", qName, []mq.Subscriber{{Name: "b", URL: "http://localhost:1"}})
+	assert.NoErr(t, err)
+	_, err = cl.RemoveSubscribers(ctx, "", "", qName, []string{"a"})
+	assert.NoErr(t, err)
+	_, err = cl.AddAlerts(ctx, "", "", qName, []mq.Alert{{Type: mq.AlertTypeFixed, Trigger: 10, Queue: "alerts"}})
+	assert.NoErr(t, err)
+	info, err := cl.GetQueue(ctx, "", "", qName)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(info.Push.Subscribers), "number of subscribers")
+	assert.Equal(t, "b", info.Push.Subscribers[0].Name, "subscriber name")
+	assert.Equal(t, 1, len(info.Alerts), "number of alerts")
+	_, err = cl.DeleteAlert(ctx, "", "", qName, info.Alerts[0].ID)
+	assert.NoErr(t, err)
+	_, err = cl.DeleteAlert(ctx, "", "", qName, info.Alerts[0].ID)
+	assert.Err(t, mq.ErrNoSuchAlert, err)
+
+	queues, err := cl.ListQueues(ctx, "", "", "", "", 0)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(queues), "number of queues")
+	assert.Equal(t, qName, queues[0].Name, "queue name")
+}
+
+func TestServerAuthorization(t *testing.T) {
+	srv := NewServer(token, projID)
+	defer srv.Close()
+	cl := srv.Client()
+	ctx := context.Background()
+
+	_, err := cl.GetQueue(ctx, "wrong-token", "", qName)
+	apiErr, ok := err.(*mq.APIError)
+	assert.True(t, ok, "error [%v] wasn't an *APIError", err)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode, "status code")
+	assert.True(t, apiErr.Msg != "", "error message was empty")
+
+	_, err = cl.GetQueue(ctx, "", "wrong-proj", qName)
+	apiErr, ok = err.(*mq.APIError)
+	assert.True(t, ok, "error [%v] wasn't an *APIError", err)
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode, "status code")
+
+	resp, err := http.Get(srv.URL() + "/3/projects/" + projID + "/queues?oauth=" + token)
+	assert.NoErr(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "status code with the token in the query string")
+}
+
+func TestServerDeleteMessages(t *testing.T) {
+	srv := NewServer(token, projID)
+	defer srv.Close()
+	ctx := context.Background()
+	newMsgs := []mq.NewMessage{
+		{Body: "1", PushHeaders: make(map[string]string)},
+		{Body: "2", PushHeaders: make(map[string]string)},
+	}
+	tests := []struct {
+		body    string
+		cleared bool
+	}{
+		{body: `{"ids":null}`, cleared: false},
+		{body: `{"ids":[]}`, cleared: false},
+		{body: `{}`, cleared: true},
+		{body: ``, cleared: true},
+	}
+	for _, test := range tests {
+		_, err := srv.Mem.ClearQueue(ctx, token, projID, qName)
+		if err != mq.ErrNoSuchQueue {
+			assert.NoErr(t, err)
+		}
+		_, err = srv.Mem.Enqueue(ctx, token, projID, qName, newMsgs)
+		assert.NoErr(t, err)
+		req, err := http.NewRequest("DELETE", srv.URL()+"/3/projects/"+projID+"/queues/"+qName+"/messages", strings.NewReader(test.body))
+		assert.NoErr(t, err)
+		req.Header.Set("Authorization", "OAuth "+token)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoErr(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "status code for body "+test.body)
+		expected := 2
+		if test.cleared {
+			expected = 0
+		}
+		info, err := srv.Mem.GetQueue(ctx, token, projID, qName)
+		assert.NoErr(t, err)
+		assert.Equal(t, expected, info.Size, "queue size after delete with body "+test.body)
+	}
+}
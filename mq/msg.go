@@ -12,8 +12,37 @@ type NewMessage struct {
 
 // DequeuedMessage represents a message that has been dequeued from IronMQ.
 type DequeuedMessage struct {
-	ID            int    `json:"id"`
+	ID            string `json:"id"`
 	Body          string `json:"body"`
 	ReservedCount int    `json:"reserved_count"`
 	ReservationID string `json:"reservation_id"`
 }
+
+// Message represents a message on an IronMQ queue, including its metadata. It's returned
+// by funcs that look at messages without reserving them
+type Message struct {
+	ID            string `json:"id"`
+	Body          string `json:"body"`
+	ReservedCount int    `json:"reserved_count"`
+	// The ID of the message's current reservation. Empty if the message isn't reserved
+	ReservationID string            `json:"reservation_id,omitempty"`
+	PushHeaders   map[string]string `json:"push_headers,omitempty"`
+	// The delivery status of the message to each subscriber. Empty unless the message is on a push queue
+	PushStatuses []PushStatus `json:"push_statuses,omitempty"`
+}
+
+// PushStatus is the delivery status of a message on a push queue to a single subscriber
+type PushStatus struct {
+	// The name of the subscriber
+	SubscriberName string `json:"subscriber_name"`
+	// The URL that the message was pushed to
+	URL string `json:"url"`
+	// The HTTP status code that the subscriber returned on the most recent try
+	StatusCode int `json:"status_code"`
+	// The number of times IronMQ has tried to push the message to the subscriber
+	Tries int `json:"tries"`
+	// The number of retries left before IronMQ gives up on the subscriber
+	RetriesRemaining int `json:"retries_remaining"`
+	// The delivery status message
+	Msg string `json:"msg"`
+}
@@ -0,0 +1,161 @@
+package mq
+
+const (
+	// QueueTypePull is the type of a queue that consumers dequeue messages from
+	QueueTypePull = "pull"
+	// QueueTypeMulticast is the type of a push queue that pushes each message to all of its subscribers
+	QueueTypeMulticast = "multicast"
+	// QueueTypeUnicast is the type of a push queue that pushes each message to one of its subscribers,
+	// trying the next subscriber when a push fails
+	QueueTypeUnicast = "unicast"
+	// DefaultPushRetries is the number of times IronMQ retries a failed push when none is specified
+	DefaultPushRetries = 3
+	// DefaultPushRetriesDelay is the number of seconds IronMQ waits between push retries when none is specified
+	DefaultPushRetriesDelay = 60
+	// DefaultMessageTimeout is the message timeout, in seconds, that IronMQ gives a queue when none is specified
+	DefaultMessageTimeout = 60
+	// DefaultMessageExpiration is the message expiration, in seconds, that IronMQ gives a queue when none is specified
+	DefaultMessageExpiration = 604800
+	// MaxMessageExpiration is the maximum value for a queue's message expiration
+	MaxMessageExpiration = 2592000
+)
+
+// QueueConfig is the configuration for a queue. It's passed to CreateQueue and UpdateQueue. Zero valued
+// fields are not sent to IronMQ, so the server uses its defaults (or the queue's existing values, on an update)
+type QueueConfig struct {
+	// MessageTimeout is the number of seconds after which a reserved message goes back onto the queue
+	MessageTimeout uint32 `json:"message_timeout,omitempty"`
+	// MessageExpiration is the number of seconds that a message stays on the queue before it's deleted
+	MessageExpiration uint32 `json:"message_expiration,omitempty"`
+	// Type is the queue type. One of QueueTypePull, QueueTypeMulticast or QueueTypeUnicast
+	Type string `json:"type,omitempty"`
+	// Push is the push configuration. Push queues must have at least one subscriber
+	Push *PushConfig `json:"push,omitempty"`
+}
+
+// isPushType returns true if typ is the type of a push queue
+func isPushType(typ string) bool {
+	return typ == QueueTypeMulticast || typ == QueueTypeUnicast
+}
+
+// Subscriber is an HTTP endpoint that a push queue pushes messages to
+type Subscriber struct {
+	// Name is the name of the subscriber. It must be unique in the queue
+	Name string `json:"name"`
+	// URL is the URL that messages are POSTed to
+	URL string `json:"url"`
+	// Headers are sent with every message that's pushed to the subscriber
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// validate returns ErrInvalidSubscriber if s is missing a name or URL
+func (s Subscriber) validate() error {
+	if s.Name == "" || s.URL == "" {
+		return ErrInvalidSubscriber
+	}
+	return nil
+}
+
+// PushConfig is the configuration for a push queue
+type PushConfig struct {
+	// Subscribers are the endpoints that messages are pushed to
+	Subscribers []Subscriber `json:"subscribers,omitempty"`
+	// Retries is the number of times to retry a failed push to a subscriber
+	Retries int `json:"retries,omitempty"`
+	// RetriesDelay is the number of seconds to wait between retries
+	RetriesDelay int `json:"retries_delay,omitempty"`
+	// ErrorQueue is the name of the queue that a message is enqueued onto when all pushes
+	// of it to a subscriber fail. Leave it empty to drop those messages
+	ErrorQueue string `json:"error_queue,omitempty"`
+}
+
+// clone returns a deep copy of p
+func (p *PushConfig) clone() *PushConfig {
+	if p == nil {
+		return nil
+	}
+	ret := *p
+	ret.Subscribers = append([]Subscriber(nil), p.Subscribers...)
+	return &ret
+}
+
+// validate returns a non-nil error if any non-zero value in c is out of range
+func (c QueueConfig) validate() error {
+	if c.MessageTimeout != 0 && !timeoutInRange(Timeout(c.MessageTimeout)) {
+		return ErrTimeoutOutOfRange
+	}
+	if c.MessageExpiration > MaxMessageExpiration {
+		return ErrExpirationOutOfRange
+	}
+	if c.Type != "" && c.Type != QueueTypePull && !isPushType(c.Type) {
+		return ErrInvalidQueueType
+	}
+	if isPushType(c.Type) && (c.Push == nil || len(c.Push.Subscribers) == 0) {
+		return ErrNoSubscribers
+	}
+	if c.Push != nil {
+		for _, sub := range c.Push.Subscribers {
+			if err := sub.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// update sets each field in c to the corresponding field in u if that field in u is non-zero.
+// Pull queues have no push config, so changing c to a pull queue drops c.Push
+func (c *QueueConfig) update(u QueueConfig) {
+	if u.MessageTimeout != 0 {
+		c.MessageTimeout = u.MessageTimeout
+	}
+	if u.MessageExpiration != 0 {
+		c.MessageExpiration = u.MessageExpiration
+	}
+	if u.Type != "" {
+		c.Type = u.Type
+	}
+	if u.Type == QueueTypePull {
+		c.Push = nil
+		return
+	}
+	if u.Push != nil {
+		if c.Push == nil {
+			c.Push = &PushConfig{Retries: DefaultPushRetries, RetriesDelay: DefaultPushRetriesDelay}
+		}
+		if u.Push.Subscribers != nil {
+			c.Push.Subscribers = append([]Subscriber(nil), u.Push.Subscribers...)
+		}
+		if u.Push.Retries != 0 {
+			c.Push.Retries = u.Push.Retries
+		}
+		if u.Push.RetriesDelay != 0 {
+			c.Push.RetriesDelay = u.Push.RetriesDelay
+		}
+		if u.Push.ErrorQueue != "" {
+			c.Push.ErrorQueue = u.Push.ErrorQueue
+		}
+	}
+}
+
+// QueueInfo is information about a single queue. It's returned by CreateQueue, GetQueue and UpdateQueue
+type QueueInfo struct {
+	// ProjectID is the ID of the project that the queue belongs to
+	ProjectID string `json:"project_id"`
+	// Name is the name of the queue
+	Name string `json:"name"`
+	// Size is the number of messages currently on the queue
+	Size int `json:"size"`
+	// TotalMessages is the number of messages that have ever been enqueued onto the queue
+	TotalMessages int `json:"total_messages"`
+	// Type is the queue type
+	Type string `json:"type"`
+	// MessageTimeout is the number of seconds after which a reserved message goes back onto the queue
+	MessageTimeout uint32 `json:"message_timeout"`
+	// MessageExpiration is the number of seconds that a message stays on the queue before it's deleted
+	MessageExpiration uint32 `json:"message_expiration"`
+	// Push is the push configuration. It's nil for pull queues
+	Push *PushConfig `json:"push,omitempty"`
+	// Alerts are the alerts on the queue
+	Alerts []Alert `json:"alerts,omitempty"`
+}
@@ -0,0 +1,138 @@
+package mq
+
+import (
+	"golang.org/x/net/context"
+)
+
+// Queue is a handle to a single queue that's bound to a token and project ID, so that
+// callers don't have to pass the token, project ID and queue name on every call. Queue works
+// identically on top of any Client implementation. Use NewQueue (or the Queue func on
+// HTTPClient or MemClient) to create one. See the corresponding Client funcs for
+// documentation on each of the funcs on Queue
+type Queue struct {
+	cl     Client
+	token  string
+	projID string
+	name   string
+}
+
+// NewQueue returns a Queue that runs all of its operations on the queue called name in
+// projID, using cl and token
+func NewQueue(cl Client, token, projID, name string) *Queue {
+	return &Queue{cl: cl, token: token, projID: projID, name: name}
+}
+
+// Queue returns a Queue for the queue called name in projID that uses h
+func (h *HTTPClient) Queue(token, projID, name string) *Queue {
+	return NewQueue(h, token, projID, name)
+}
+
+// Queue returns a Queue for the queue called name in projID that uses m
+func (m *MemClient) Queue(token, projID, name string) *Queue {
+	return NewQueue(m, token, projID, name)
+}
+
+// Name returns the name of the queue
+func (q *Queue) Name() string {
+	return q.name
+}
+
+// Enqueue enqueues msgs onto the queue
+func (q *Queue) Enqueue(ctx context.Context, msgs []NewMessage) (*Enqueued, error) {
+	return q.cl.Enqueue(ctx, q.token, q.projID, q.name, msgs)
+}
+
+// Dequeue dequeues at most num messages from the queue
+func (q *Queue) Dequeue(ctx context.Context, num int, timeout Timeout, wait Wait, delete bool) ([]DequeuedMessage, error) {
+	return q.cl.Dequeue(ctx, q.token, q.projID, q.name, num, timeout, wait, delete)
+}
+
+// DeleteReserved deletes a reserved message from the queue
+func (q *Queue) DeleteReserved(ctx context.Context, messageID string, reservationID string) (*Deleted, error) {
+	return q.cl.DeleteReserved(ctx, q.token, q.projID, q.name, messageID, reservationID)
+}
+
+// DeleteReservedBatch deletes reserved messages from the queue in a single operation
+func (q *Queue) DeleteReservedBatch(ctx context.Context, msgs []ReservedMessage) (*DeletedBatch, error) {
+	return q.cl.DeleteReservedBatch(ctx, q.token, q.projID, q.name, msgs)
+}
+
+// Create creates the queue with the given configuration
+func (q *Queue) Create(ctx context.Context, conf QueueConfig) (*QueueInfo, error) {
+	return q.cl.CreateQueue(ctx, q.token, q.projID, q.name, conf)
+}
+
+// Info returns information about the queue
+func (q *Queue) Info(ctx context.Context) (*QueueInfo, error) {
+	return q.cl.GetQueue(ctx, q.token, q.projID, q.name)
+}
+
+// Update updates the queue's configuration
+func (q *Queue) Update(ctx context.Context, conf QueueConfig) (*QueueInfo, error) {
+	return q.cl.UpdateQueue(ctx, q.token, q.projID, q.name, conf)
+}
+
+// Delete deletes the queue and all of its messages
+func (q *Queue) Delete(ctx context.Context) (*Deleted, error) {
+	return q.cl.DeleteQueue(ctx, q.token, q.projID, q.name)
+}
+
+// Clear deletes all of the messages on the queue
+func (q *Queue) Clear(ctx context.Context) (*Cleared, error) {
+	return q.cl.ClearQueue(ctx, q.token, q.projID, q.name)
+}
+
+// Peek returns at most num messages from the front of the queue without reserving them
+func (q *Queue) Peek(ctx context.Context, num int) ([]Message, error) {
+	return q.cl.Peek(ctx, q.token, q.projID, q.name, num)
+}
+
+// GetMessage returns a single message from the queue
+func (q *Queue) GetMessage(ctx context.Context, messageID string) (*Message, error) {
+	return q.cl.GetMessage(ctx, q.token, q.projID, q.name, messageID)
+}
+
+// GetPushStatuses returns the delivery status of a pushed message for each subscriber
+func (q *Queue) GetPushStatuses(ctx context.Context, messageID string) ([]PushStatus, error) {
+	return q.cl.GetPushStatuses(ctx, q.token, q.projID, q.name, messageID)
+}
+
+// Touch extends a reservation on a message in the queue
+func (q *Queue) Touch(ctx context.Context, messageID string, reservationID string, timeout Timeout) (*Touched, error) {
+	return q.cl.Touch(ctx, q.token, q.projID, q.name, messageID, reservationID, timeout)
+}
+
+// Release releases a reservation on a message in the queue
+func (q *Queue) Release(ctx context.Context, messageID string, reservationID string, delay uint32) (*Released, error) {
+	return q.cl.Release(ctx, q.token, q.projID, q.name, messageID, reservationID, delay)
+}
+
+// AddAlerts adds alerts to the queue
+func (q *Queue) AddAlerts(ctx context.Context, alerts []Alert) (*Updated, error) {
+	return q.cl.AddAlerts(ctx, q.token, q.projID, q.name, alerts)
+}
+
+// ReplaceAlerts replaces all of the alerts on the queue
+func (q *Queue) ReplaceAlerts(ctx context.Context, alerts []Alert) (*Updated, error) {
+	return q.cl.ReplaceAlerts(ctx, q.token, q.projID, q.name, alerts)
+}
+
+// DeleteAlert removes an alert from the queue
+func (q *Queue) DeleteAlert(ctx context.Context, alertID string) (*Deleted, error) {
+	return q.cl.DeleteAlert(ctx, q.token, q.projID, q.name, alertID)
+}
+
+// AddSubscribers adds subscribers to the push queue
+func (q *Queue) AddSubscribers(ctx context.Context, subs []Subscriber) (*Updated, error) {
+	return q.cl.AddSubscribers(ctx, q.token, q.projID, q.name, subs)
+}
+
+// ReplaceSubscribers replaces all of the subscribers on the push queue
+func (q *Queue) ReplaceSubscribers(ctx context.Context, subs []Subscriber) (*Updated, error) {
+	return q.cl.ReplaceSubscribers(ctx, q.token, q.projID, q.name, subs)
+}
+
+// RemoveSubscribers removes subscribers from the push queue
+func (q *Queue) RemoveSubscribers(ctx context.Context, names []string) (*Updated, error) {
+	return q.cl.RemoveSubscribers(ctx, q.token, q.projID, q.name, names)
+}
@@ -0,0 +1,36 @@
+package mq
+
+import (
+	"testing"
+
+	"github.com/arschles/assert"
+	"golang.org/x/net/context"
+)
+
+func queueHandleOperations(t *testing.T, q *Queue) {
+	ctx := context.Background()
+	_, err := q.Create(ctx, QueueConfig{})
+	assert.NoErr(t, err)
+	_, err = q.Enqueue(ctx, []NewMessage{{Body: "123", Delay: 0, PushHeaders: make(map[string]string)}})
+	assert.NoErr(t, err)
+	info, err := q.Info(ctx)
+	assert.NoErr(t, err)
+	assert.Equal(t, q.Name(), info.Name, "queue name")
+	assert.Equal(t, 1, info.Size, "queue size")
+	msgs, err := q.Dequeue(ctx, 1, Timeout(30), Wait(1), false)
+	assert.NoErr(t, err)
+	assert.Equal(t, 1, len(msgs), "number of dequeued messages")
+	_, err = q.DeleteReserved(ctx, msgs[0].ID, msgs[0].ReservationID)
+	assert.NoErr(t, err)
+	_, err = q.Delete(ctx)
+	assert.NoErr(t, err)
+}
+
+func TestMemQueueHandle(t *testing.T) {
+	cl := NewMemClient()
+	q := cl.Queue(token, projID, qName)
+	queueHandleOperations(t, q)
+	// make sure the handle operated on the queue it was bound to
+	_, err := cl.GetQueue(context.Background(), token, projID, qName)
+	assert.Err(t, ErrNoSuchQueue, err)
+}
@@ -0,0 +1,74 @@
+package mq
+
+import (
+	"golang.org/x/net/context"
+)
+
+// QueueIterator walks all of the queues in a project, fetching pages with
+// ListQueues as needed. Use NewQueueIterator to create one. Example usage:
+//
+//	iter := NewQueueIterator(client, token, projID, "", 0)
+//	for iter.Next(ctx) {
+//	  fmt.Println(iter.Queue().Name)
+//	}
+//	if err := iter.Err(); err != nil {
+//	  return err
+//	}
+type QueueIterator struct {
+	cl      Client
+	token   string
+	projID  string
+	prefix  string
+	perPage int
+	page    []QueueInfo
+	cur     QueueInfo
+	last    bool
+	err     error
+}
+
+// NewQueueIterator returns a QueueIterator that walks all queues in projID whose names
+// start with prefix, fetching perPage queues at a time from cl. Pass 0 as perPage to
+// use DefaultPerPage
+func NewQueueIterator(cl Client, token, projID, prefix string, perPage int) *QueueIterator {
+	if perPage == 0 {
+		perPage = DefaultPerPage
+	}
+	return &QueueIterator{cl: cl, token: token, projID: projID, prefix: prefix, perPage: perPage}
+}
+
+// Next advances the iterator to the next queue, fetching the next page if necessary.
+// Returns false when there are no more queues or an error occurred. Call Err to tell
+// the difference
+func (q *QueueIterator) Next(ctx context.Context) bool {
+	if q.err != nil {
+		return false
+	}
+	if len(q.page) == 0 {
+		if q.last {
+			return false
+		}
+		page, err := q.cl.ListQueues(ctx, q.token, q.projID, q.prefix, q.cur.Name, q.perPage)
+		if err != nil {
+			q.err = err
+			return false
+		}
+		q.page = page
+		q.last = len(page) < q.perPage
+		if len(q.page) == 0 {
+			return false
+		}
+	}
+	q.cur = q.page[0]
+	q.page = q.page[1:]
+	return true
+}
+
+// Queue returns the queue that the most recent call to Next advanced to
+func (q *QueueIterator) Queue() QueueInfo {
+	return q.cur
+}
+
+// Err returns the error, if any, that caused Next to return false
+func (q *QueueIterator) Err() error {
+	return q.err
+}
@@ -0,0 +1,111 @@
+package mq
+
+import (
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// RetryPolicy configures how an HTTPClient retries requests that fail with transient
+// errors. Only requests with idempotent or safe methods (GET, HEAD, OPTIONS, PUT and
+// DELETE) are retried, and only when they fail with a network error or a 429, 502, 503 or
+// 504 status code. That means Enqueue, Dequeue and the other funcs that POST are never
+// retried, because IronMQ may have applied them before failing.
+//
+// Retries wait for an exponentially increasing, jittered backoff, or for the duration
+// in the response's Retry-After header if there is one. They never wait longer than
+// MaxBackoff, even if Retry-After asks for more, or past the deadline of the
+// context.Context passed to the func being retried
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times to send a request, including the first
+	// time. Values less than 2 disable retries
+	MaxAttempts int
+	// InitialBackoff is the base backoff before the first retry. It doubles on each subsequent retry
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum backoff between two retries. It also caps the wait that a
+	// response's Retry-After header asks for
+	MaxBackoff time.Duration
+}
+
+var (
+	// DefaultRetryPolicy is the RetryPolicy that NewHTTPClient uses
+	DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 5 * time.Second}
+	// NoRetries is a RetryPolicy that never retries
+	NoRetries = RetryPolicy{MaxAttempts: 1}
+)
+
+// backoff returns the jittered backoff to wait before retry number attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	b := p.InitialBackoff
+	for i := 1; i < attempt && b < p.MaxBackoff; i++ {
+		b *= 2
+	}
+	if b > p.MaxBackoff {
+		b = p.MaxBackoff
+	}
+	if b <= 0 {
+		return 0
+	}
+	// equal jitter: wait at least half of the backoff
+	return b/2 + time.Duration(rand.Int63n(int64(b/2)+1))
+}
+
+// wait waits before retrying a request that failed with err on attempt number attempt.
+// Returns err immediately if the wait would run past ctx's deadline, and ctx.Err() if
+// ctx.Done() receives while waiting
+func (p RetryPolicy) wait(ctx context.Context, attempt int, err error) error {
+	d := p.backoff(attempt)
+	if apiErr, ok := err.(*APIError); ok && apiErr.retryAfter > 0 {
+		d = apiErr.retryAfter
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(d).After(deadline) {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// retryable returns true if req failed with err and can be safely retried
+func retryable(req *http.Request, err error) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+	default:
+		return false
+	}
+	switch e := err.(type) {
+	case *APIError:
+		switch e.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	case *url.Error:
+		return true
+	default:
+		return false
+	}
+}
+
+// rewind returns a copy of req with a fresh body, so it can be sent again
+func rewind(req *http.Request) (*http.Request, error) {
+	ret := new(http.Request)
+	*ret = *req
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		ret.Body = body
+	}
+	return ret, nil
+}
@@ -0,0 +1,104 @@
+package mq_test
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arschles/assert"
+	"github.com/arschles/gorion/mq"
+	"github.com/arschles/gorion/mq/mqtest"
+	"github.com/arschles/testsrv"
+	"golang.org/x/net/context"
+)
+
+// flakyHandler returns a handler that responds with status code and the given Retry-After
+// header to the first failures requests, then forwards to hndl. Increments *count on every request
+func flakyHandler(failures int32, code int, retryAfter string, count *int32, hndl http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(count, 1) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			http.Error(w, `{"msg":"service unavailable"}`, code)
+			return
+		}
+		hndl.ServeHTTP(w, r)
+	})
+}
+
+func testRetryPolicy() mq.RetryPolicy {
+	return mq.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	var count int32
+	srv := testsrv.StartServer(flakyHandler(2, http.StatusServiceUnavailable, "", &count, mqtest.NewHandler(mq.NewMemClient(), token, projID)))
+	defer srv.Close()
+	cl := newTestHTTPClient(t, srv, mq.WithRetryPolicy(testRetryPolicy()))
+	_, err := cl.CreateQueue(bgCtx, token, projID, qName, mq.QueueConfig{})
+	assert.NoErr(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count), "number of requests")
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var count int32
+	srv := testsrv.StartServer(flakyHandler(5, http.StatusServiceUnavailable, "", &count, mqtest.NewHandler(mq.NewMemClient(), token, projID)))
+	defer srv.Close()
+	cl := newTestHTTPClient(t, srv, mq.WithRetryPolicy(testRetryPolicy()))
+	_, err := cl.GetQueue(bgCtx, token, projID, qName)
+	apiErr, ok := err.(*mq.APIError)
+	assert.True(t, ok, "returned error [%s] was not an *APIError", err)
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode, "status code")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count), "number of requests")
+}
+
+func TestNoRetryNonIdempotent(t *testing.T) {
+	var count int32
+	srv := testsrv.StartServer(flakyHandler(1, http.StatusServiceUnavailable, "", &count, mqtest.NewHandler(mq.NewMemClient(), token, projID)))
+	defer srv.Close()
+	cl := newTestHTTPClient(t, srv, mq.WithRetryPolicy(testRetryPolicy()))
+	_, err := cl.Enqueue(bgCtx, token, projID, qName, []mq.NewMessage{{Body: "123", PushHeaders: make(map[string]string)}})
+	_, ok := err.(*mq.APIError)
+	assert.True(t, ok, "returned error [%s] was not an *APIError", err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count), "number of requests")
+}
+
+func TestRetryAfterPastDeadline(t *testing.T) {
+	var count int32
+	srv := testsrv.StartServer(flakyHandler(1, http.StatusServiceUnavailable, "30", &count, mqtest.NewHandler(mq.NewMemClient(), token, projID)))
+	defer srv.Close()
+	// a MaxBackoff past the deadline, so Retry-After isn't capped below it
+	cl := newTestHTTPClient(t, srv, mq.WithRetryPolicy(mq.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Minute}))
+	ctx, cancel := context.WithTimeout(bgCtx, time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := cl.GetQueue(ctx, token, projID, qName)
+	_, ok := err.(*mq.APIError)
+	assert.True(t, ok, "returned error [%s] was not an *APIError", err)
+	assert.True(t, time.Since(start) < time.Second, "GetQueue waited [%s] for a retry past its deadline", time.Since(start))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count), "number of requests")
+}
+
+func TestRetryAfterCappedAtMaxBackoff(t *testing.T) {
+	var count int32
+	srv := testsrv.StartServer(flakyHandler(1, http.StatusServiceUnavailable, "30", &count, mqtest.NewHandler(mq.NewMemClient(), token, projID)))
+	defer srv.Close()
+	cl := newTestHTTPClient(t, srv, mq.WithRetryPolicy(testRetryPolicy()))
+	// without the cap, the 30 second Retry-After would run past the deadline and fail the request
+	ctx, cancel := context.WithTimeout(bgCtx, 5*time.Second)
+	defer cancel()
+	_, err := cl.CreateQueue(ctx, token, projID, qName, mq.QueueConfig{})
+	assert.NoErr(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count), "number of requests")
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := mq.RetryPolicy{MaxAttempts: 10, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt := 1; attempt < 10; attempt++ {
+		b := p.Backoff(attempt)
+		assert.True(t, b <= p.MaxBackoff, "backoff [%s] for attempt [%d] was greater than the max", b, attempt)
+		assert.True(t, b >= p.InitialBackoff/2, "backoff [%s] for attempt [%d] was less than half the initial backoff", b, attempt)
+	}
+}